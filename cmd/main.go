@@ -2,12 +2,55 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"skillkit/lib"
 )
 
+// outputFormat 全局输出格式 (--json / --format)
+var outputFormat = lib.FormatText
+
+// structuredOutput 是否输出 JSON/YAML
+func structuredOutput() bool {
+	return outputFormat != lib.FormatText
+}
+
+// reportOut 报告的输出目标
+var reportOut io.Writer = os.Stdout
+
+// textOut 文本输出（提示、警告、错误）的目标
+// 使用 --json/--format 时为 stderr，stdout 只输出报告
+var textOut io.Writer = os.Stdout
+
+// writeReport 以全局输出格式输出报告
+func writeReport(report any) {
+	if err := lib.WriteReport(reportOut, outputFormat, report); err != nil {
+		fmt.Fprintf(os.Stderr, "%s %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+}
+
+// projectRoot 全局选项 --root 指定的项目根目录，为空时自动查找
+var projectRoot string
+
+// loadConfig 加载配置并应用 --root
+func loadConfig() (*lib.Config, error) {
+	cfg, err := lib.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if projectRoot != "" {
+		cfg.SetProjectRoot(projectRoot)
+	}
+	return cfg, nil
+}
+
 func main() {
 	if len(os.Args) < 2 {
 		// 无参数时显示交互式菜单（循环）
@@ -23,8 +66,27 @@ func main() {
 		return
 	}
 
-	cmd := os.Args[1]
-	args := os.Args[2:]
+	format, argv, err := lib.ParseOutputFlags(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+	outputFormat = format
+	if structuredOutput() {
+		textOut = os.Stderr
+	}
+	projectRoot, argv, err = lib.ParseRootFlag(argv)
+	if err != nil {
+		fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+	if len(argv) == 0 {
+		lib.ShowHelp()
+		return
+	}
+
+	cmd := argv[0]
+	args := argv[1:]
 
 	switch cmd {
 	case "--help", "-h", "help":
@@ -40,6 +102,20 @@ func handleCommand(cmd string, args []string) {
 	switch cmd {
 	case "add":
 		handleAdd(args)
+	case "update":
+		handleUpdate(args)
+	case "restore":
+		handleRestore(args)
+	case "install":
+		handleInstall(args)
+	case "vendor":
+		handleVendor(args)
+	case "search":
+		handleSearch(args)
+	case "registry":
+		handleRegistry(args)
+	case "cache":
+		handleCache(args)
 	case "use":
 		handleUse(args)
 	case "list":
@@ -54,21 +130,27 @@ func handleCommand(cmd string, args []string) {
 		handleSync(args)
 	case "status":
 		handleStatus(args)
+	case "doctor":
+		handleDoctor(args)
+	case "lint":
+		handleLint(args)
+	case "new":
+		handleNew(args)
 	case "init":
 		handleInit(args)
 	default:
-		fmt.Printf("%s Unknown command: %s\n", lib.Red(lib.IconError), cmd)
-		fmt.Println("Run 'sk --help' for usage information.")
+		fmt.Fprintf(textOut, "%s Unknown command: %s\n", lib.Red(lib.IconError), cmd)
+		fmt.Fprintln(textOut, "Run 'sk --help' for usage information.")
 		os.Exit(1)
 	}
 }
 
 // handleInteractiveCommand 处理交互模式下的命令，返回 true 继续循环，false 退出
 func handleInteractiveCommand(cmd string) bool {
-	cfg, err := lib.LoadConfig()
+	cfg, err := loadConfig()
 	if err != nil {
 		lib.ClearScreen()
-		fmt.Printf("%s Error loading config: %v\n", lib.Red(lib.IconError), err)
+		fmt.Fprintf(textOut, "%s Error loading config: %v\n", lib.Red(lib.IconError), err)
 		return true
 	}
 
@@ -105,7 +187,7 @@ func handleInteractiveCommand(cmd string) bool {
 
 	default:
 		lib.ClearScreen()
-		fmt.Printf("%s Unknown command: %s\n", lib.Red(lib.IconError), cmd)
+		fmt.Fprintf(textOut, "%s Unknown command: %s\n", lib.Red(lib.IconError), cmd)
 	}
 
 	return true
@@ -154,19 +236,8 @@ func syncModuleToDefaultPlatforms(cfg *lib.Config, mod *lib.Module) bool {
 	}
 
 	lib.ClearScreen()
-	fmt.Println()
-	for platKey, p := range targetPlatforms {
-		ln := mod.GetLinkName(platKey)
-		targetDir := lib.ResolvePath(p.Global, p.GetCategoryDir(mod.Category))
-		targetPath := targetDir + "/" + ln
-
-		err := lib.CreateSymlink(mod.Path, targetPath, false)
-		if err != nil {
-			fmt.Printf("  %s %s → %s: %v\n", lib.Red(lib.IconError), mod.Name, platKey, err)
-		} else {
-			fmt.Printf("  %s %s %s %s\n", lib.Green(lib.IconSuccess), mod.Name, lib.Cyan(lib.IconLink), p.Name)
-		}
-	}
+	fmt.Fprintln(textOut)
+	printPlanResults(cfg, lib.ApplyPlan(lib.PlanLinks([]*lib.Module{mod}, targetPlatforms), false))
 	return true
 }
 
@@ -184,29 +255,111 @@ func syncAllModulesToDefaultPlatforms(cfg *lib.Config, modules []*lib.Module) bo
 	}
 
 	lib.ClearScreen()
-	fmt.Println()
+	fmt.Fprintln(textOut)
+	success, failed := printPlanResults(cfg, lib.ApplyPlan(lib.PlanLinks(modules, targetPlatforms), false))
+	fmt.Fprintln(textOut)
+	fmt.Fprintf(textOut, "  %s: %d  %s: %d\n", lib.Green("Success"), success, lib.Red("Failed"), failed)
+	lib.WaitForKey()
+	return true
+}
+
+// printPlanTable 以表格输出分发计划
+func printPlanTable(plan *lib.Plan) {
+	headers := []string{"Module", "Platform", "Target Path", "Action"}
+	var rows [][]string
+	for _, op := range plan.Ops {
+		rows = append(rows, []string{op.Module, op.Platform, op.Target, op.Action})
+	}
+	lib.PrintTable(textOut, headers, rows)
+}
+
+// printPlanResults 输出分发结果，返回成功和失败的数量
+func printPlanResults(cfg *lib.Config, results []lib.OpResult) (int, int) {
 	success := 0
 	failed := 0
-	for _, mod := range modules {
-		for platKey, p := range targetPlatforms {
-			ln := mod.GetLinkName(platKey)
-			targetDir := lib.ResolvePath(p.Global, p.GetCategoryDir(mod.Category))
-			targetPath := targetDir + "/" + ln
+	for _, r := range results {
+		name := r.Op.Platform
+		if p, ok := cfg.Platforms[name]; ok {
+			name = p.Name
+		}
+		switch {
+		case r.Err == lib.ErrAborted:
+			fmt.Fprintf(textOut, "  %s %s → %s: %v\n", lib.Gray("○"), r.Op.Module, r.Op.Platform, r.Err)
+			failed++
+		case r.Err != nil:
+			fmt.Fprintf(textOut, "  %s %s → %s: %v\n", lib.Red(lib.IconError), r.Op.Module, r.Op.Platform, r.Err)
+			failed++
+		case r.RolledBack:
+			fmt.Fprintf(textOut, "  %s %s → %s: rolled back\n", lib.Yellow(lib.IconWarning), r.Op.Module, r.Op.Platform)
+			failed++
+		default:
+			fmt.Fprintf(textOut, "  %s %s %s %s\n", lib.Green(lib.IconSuccess), r.Op.Module, lib.Cyan(lib.IconLink), name)
+			success++
+		}
+	}
+	return success, failed
+}
 
-			err := lib.CreateSymlink(mod.Path, targetPath, false)
-			if err != nil {
-				fmt.Printf("  %s %s → %s: %v\n", lib.Red(lib.IconError), mod.Name, platKey, err)
-				failed++
-			} else {
-				fmt.Printf("  %s %s %s %s\n", lib.Green(lib.IconSuccess), mod.Name, lib.Cyan(lib.IconLink), p.Name)
-				success++
-			}
+// printProjectRoot 作用域包含项目时显示项目根目录，未找到项目时提示使用当前目录
+func printProjectRoot(cfg *lib.Config, scopes []string) {
+	for _, scope := range scopes {
+		if scope != lib.ScopeProject {
+			continue
+		}
+		if cfg.InProject() {
+			fmt.Fprintf(textOut, "  %s %s\n\n", lib.Gray("Project root:"), cfg.ProjectRoot)
+		} else {
+			fmt.Fprintf(textOut, "  %s %s %s\n\n", lib.Gray("Project root:"), cfg.ProjectDir(),
+				lib.Yellow("(no .git, "+lib.ManifestName+" or platform dir found, using current directory; pass --root to override)"))
 		}
 	}
-	fmt.Println()
-	fmt.Printf("  %s: %d  %s: %d\n", lib.Green("Success"), success, lib.Red("Failed"), failed)
-	lib.WaitForKey()
-	return true
+}
+
+// planReport 生成 dry-run 报告，作用域包含项目时记录项目根目录
+func planReport(cfg *lib.Config, plan *lib.Plan, scopes []string) lib.PlanReport {
+	report := lib.NewPlanReport(plan)
+	for _, scope := range scopes {
+		if scope == lib.ScopeProject {
+			report.ProjectRoot = cfg.ProjectDir()
+		}
+	}
+	return report
+}
+
+// useRelativeLinks 处理 --relative：软链接平台改为创建相对软链接，复制/硬链接平台不变
+func useRelativeLinks(cfg *lib.Config) {
+	for key, p := range cfg.Platforms {
+		if p.GetLinkMode() == lib.LinkModeSymlink {
+			p.LinkMode = lib.LinkModeRelative
+			cfg.Platforms[key] = p
+		}
+	}
+}
+
+// sortedPlatformKeys 返回排序后的平台 key
+func sortedPlatformKeys(platforms map[string]lib.Platform) []string {
+	keys := make([]string, 0, len(platforms))
+	for key := range platforms {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// scopedName 项目作用域的平台名加上 "(project)" 标注
+func scopedName(name, scope string) string {
+	if scope == lib.ScopeProject {
+		return name + " (project)"
+	}
+	return name
+}
+
+// scopeTitle 作用域名称（首字母大写），用于提示信息
+func scopeTitle(scope string) string {
+	if scope == lib.ScopeProject {
+		return "Project"
+	}
+	return "Global"
 }
 
 // getTargetPlatforms 获取目标平台（默认平台或全部平台）
@@ -226,9 +379,9 @@ func getTargetPlatforms(cfg *lib.Config) map[string]lib.Platform {
 func warnNoTargetPlatforms(cfg *lib.Config) {
 	lib.ClearScreen()
 	if len(cfg.DefaultPlatforms) > 0 {
-		fmt.Printf("\n%s No valid default platforms configured.\n\n", lib.Yellow(lib.IconWarning))
+		fmt.Fprintf(textOut, "\n%s No valid default platforms configured.\n\n", lib.Yellow(lib.IconWarning))
 	} else {
-		fmt.Printf("\n%s No platforms configured.\n\n", lib.Yellow(lib.IconWarning))
+		fmt.Fprintf(textOut, "\n%s No platforms configured.\n\n", lib.Yellow(lib.IconWarning))
 	}
 	lib.WaitForKey()
 }
@@ -248,35 +401,31 @@ func handleModuleDetail(cfg *lib.Config, mod *lib.Module) bool {
 				msg := fmt.Sprintf("Apply changes? (+%d sync, -%d remove)", len(detailResult.ToSync), len(detailResult.ToRemove))
 				if lib.ConfirmDialog(msg) {
 					lib.ClearScreen()
-					fmt.Println()
+					fmt.Fprintln(textOut)
 
 					// 执行同步
-					for _, platKey := range detailResult.ToSync {
-						p := cfg.Platforms[platKey]
-						ln := mod.GetLinkName(platKey)
-						targetDir := lib.ResolvePath(p.Global, p.GetCategoryDir(mod.Category))
-						targetPath := targetDir + "/" + ln
+					for _, t := range detailResult.ToSync {
+						p := cfg.Platforms[t.Key]
+						targetPath := lib.ModuleTarget(p, t.Key, mod, t.Scope)
 
-						err := lib.CreateSymlink(mod.Path, targetPath, false)
+						err := lib.Distribute(mod.Path, targetPath, p.GetLinkMode())
 						if err != nil {
-							fmt.Printf("  %s %s → %s: %v\n", lib.Red(lib.IconError), mod.Name, platKey, err)
+							fmt.Fprintf(textOut, "  %s %s → %s: %v\n", lib.Red(lib.IconError), mod.Name, scopedName(t.Key, t.Scope), err)
 						} else {
-							fmt.Printf("  %s %s %s %s\n", lib.Green(lib.IconSuccess), mod.Name, lib.Cyan(lib.IconLink), p.Name)
+							fmt.Fprintf(textOut, "  %s %s %s %s\n", lib.Green(lib.IconSuccess), mod.Name, lib.Cyan(lib.IconLink), scopedName(p.Name, t.Scope))
 						}
 					}
 
 					// 执行删除
-					for _, platKey := range detailResult.ToRemove {
-						p := cfg.Platforms[platKey]
-						ln := mod.GetLinkName(platKey)
-						targetDir := lib.ResolvePath(p.Global, p.GetCategoryDir(mod.Category))
-						targetPath := targetDir + "/" + ln
+					for _, t := range detailResult.ToRemove {
+						p := cfg.Platforms[t.Key]
+						targetPath := lib.ModuleTarget(p, t.Key, mod, t.Scope)
 
-						err := lib.RemoveSymlink(targetPath)
+						err := lib.Undistribute(targetPath)
 						if err != nil {
-							fmt.Printf("  %s Remove %s from %s: %v\n", lib.Red(lib.IconError), mod.Name, platKey, err)
+							fmt.Fprintf(textOut, "  %s Remove %s from %s: %v\n", lib.Red(lib.IconError), mod.Name, scopedName(t.Key, t.Scope), err)
 						} else {
-							fmt.Printf("  %s Removed from %s\n", lib.Yellow(lib.IconWarning), p.Name)
+							fmt.Fprintf(textOut, "  %s Removed from %s\n", lib.Yellow(lib.IconWarning), scopedName(p.Name, t.Scope))
 						}
 					}
 					return true
@@ -326,314 +475,1541 @@ func handleInteractiveRemove(cfg *lib.Config) bool {
 		if platResult.Key != "" {
 			args = append(args, platResult.Key)
 		}
+		// 在项目中运行时同时移除项目目录中的链接
+		if cfg.InProject() {
+			args = append(args, "--all-scopes")
+		}
 		handleRemove(args)
 		return true
 	}
 }
 
 func handleAdd(args []string) {
-	if len(args) < 1 {
-		fmt.Println("Usage: sk add <source>")
-		fmt.Println()
-		fmt.Println("Source formats:")
-		fmt.Println("  owner/repo                    GitHub shorthand")
-		fmt.Println("  owner/repo/path/to/skill      GitHub with subpath")
-		fmt.Println("  https://github.com/owner/repo GitHub URL")
-		fmt.Println("  ./local/path                  Local directory")
-		os.Exit(1)
+	source := ""
+	ref := ""
+	checksum := ""
+	offline := false
+	missingValue := false
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--offline":
+			offline = true
+		case "--ref":
+			if i+1 < len(args) {
+				ref = args[i+1]
+				i++
+			} else {
+				missingValue = true
+			}
+		case "--sha256":
+			if i+1 < len(args) {
+				checksum = args[i+1]
+				i++
+			} else {
+				missingValue = true
+			}
+		default:
+			if source == "" && !hasPrefix(args[i], "--") {
+				source = args[i]
+			}
+		}
 	}
 
-	source := args[0]
-	parsed := lib.ParseSource(source)
-
-	fmt.Printf("\n%s Parsing source: %s\n", lib.Blue(lib.IconInfo), source)
+	if source == "" || missingValue {
+		fmt.Fprintln(textOut, "Usage: sk add <source> [--ref <branch|tag|commit>] [--sha256 <hex>] [--offline]")
+		fmt.Fprintln(textOut)
+		fmt.Fprintln(textOut, "Source formats:")
+		fmt.Fprintln(textOut, "  owner/repo                    GitHub shorthand")
+		fmt.Fprintln(textOut, "  owner/repo@<ref>              GitHub pinned to a branch, tag or commit")
+		fmt.Fprintln(textOut, "  owner/repo/path/to/skill      GitHub with subpath")
+		fmt.Fprintln(textOut, "  https://github.com/owner/repo GitHub URL")
+		fmt.Fprintln(textOut, "  https://<host>/.../tree/...   GitLab, Gitea, Bitbucket or a [[hosts]] entry")
+		fmt.Fprintln(textOut, "  https://host/skills.zip       Archive (.zip, .tar.gz, .tgz, .tar)")
+		fmt.Fprintln(textOut, "  ./local/path                  Local directory or archive")
+		os.Exit(1)
+	}
 
-	var searchPath string
-	var tempDir string
+	// 加载配置
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+	if offline {
+		cfg.Offline = true
+	}
 
-	if parsed.Type == "local" {
-		// 本地路径
-		if _, err := os.Stat(parsed.LocalPath); os.IsNotExist(err) {
-			fmt.Printf("%s Path not found: %s\n", lib.Red(lib.IconError), parsed.LocalPath)
+	parsed := lib.ParseSourceWith(source, cfg.HostRegistry())
+	if ref != "" {
+		if parsed.Type == "local" {
+			fmt.Fprintf(textOut, "%s --ref cannot be used with a local path\n", lib.Red(lib.IconError))
 			os.Exit(1)
 		}
-		searchPath = parsed.LocalPath
-		fmt.Printf("%s Using local path: %s\n", lib.Green(lib.IconSuccess), searchPath)
-	} else {
-		// 远程仓库，需要克隆
-		fmt.Printf("%s Cloning %s...\n", lib.Blue(lib.IconInfo), parsed.URL)
-		var err error
-		tempDir, err = lib.CloneRepo(parsed.URL, parsed.Ref)
-		if err != nil {
-			fmt.Printf("%s %v\n", lib.Red(lib.IconError), err)
+		if parsed.Type == "archive" {
+			fmt.Fprintf(textOut, "%s --ref cannot be used with an archive\n", lib.Red(lib.IconError))
+			os.Exit(1)
+		}
+		parsed.Ref = ref
+	}
+	if checksum != "" {
+		if parsed.Type != "archive" {
+			fmt.Fprintf(textOut, "%s --sha256 can only be used with an archive source\n", lib.Red(lib.IconError))
+			os.Exit(1)
+		}
+		if !lib.IsSHA256(checksum) {
+			fmt.Fprintf(textOut, "%s Invalid --sha256: expected 64 hex characters\n", lib.Red(lib.IconError))
 			os.Exit(1)
 		}
-		defer lib.CleanupTempDir(tempDir)
-		searchPath = tempDir
-		fmt.Printf("%s Cloned to temp directory\n", lib.Green(lib.IconSuccess))
+		parsed.SHA256 = strings.ToLower(checksum)
+	}
+
+	fmt.Fprintf(textOut, "\n%s Parsing source: %s\n", lib.Blue(lib.IconInfo), lib.Redact(source))
+
+	if parsed.Type == "local" {
+		fmt.Fprintf(textOut, "%s Using local path: %s\n", lib.Green(lib.IconSuccess), parsed.LocalPath)
+	} else if parsed.Type == "archive" && parsed.LocalPath != "" {
+		fmt.Fprintf(textOut, "%s Using local archive: %s\n", lib.Green(lib.IconSuccess), parsed.LocalPath)
+	} else if parsed.Type == "archive" {
+		fmt.Fprintf(textOut, "%s Downloading %s...\n", lib.Blue(lib.IconInfo), lib.Redact(parsed.URL))
+	} else if cfg.Offline {
+		fmt.Fprintf(textOut, "%s Resolving %s offline...\n", lib.Blue(lib.IconInfo), lib.Redact(parsed.URL))
+	} else if parsed.Ref != "" {
+		fmt.Fprintf(textOut, "%s Cloning %s at %s...\n", lib.Blue(lib.IconInfo), lib.Redact(parsed.URL), parsed.Ref)
+	} else {
+		fmt.Fprintf(textOut, "%s Cloning %s...\n", lib.Blue(lib.IconInfo), lib.Redact(parsed.URL))
+	}
+
+	src, err := lib.FetchSource(cfg, parsed)
+	if err != nil {
+		fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+	defer src.Cleanup()
+	searchPath := src.Dir
+	if parsed.Type == "archive" {
+		fmt.Fprintf(textOut, "%s Extracted to temp directory %s\n", lib.Green(lib.IconSuccess), lib.Gray("(sha256 "+src.SHA256+")"))
+	} else if parsed.Type != "local" {
+		fmt.Fprintf(textOut, "%s Checked out to temp directory\n", lib.Green(lib.IconSuccess))
 	}
 
 	// 发现技能
-	fmt.Printf("%s Discovering skills...\n", lib.Blue(lib.IconInfo))
+	fmt.Fprintf(textOut, "%s Discovering skills...\n", lib.Blue(lib.IconInfo))
 	skills, err := lib.DiscoverSkills(searchPath, parsed.Subpath)
 	if err != nil {
-		fmt.Printf("%s %v\n", lib.Red(lib.IconError), err)
+		fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), err)
 		os.Exit(1)
 	}
 
 	if len(skills) == 0 {
-		fmt.Printf("%s No skills found in source\n", lib.Yellow(lib.IconWarning))
+		fmt.Fprintf(textOut, "%s No skills found in source\n", lib.Yellow(lib.IconWarning))
 		os.Exit(0)
 	}
 
 	// 选择要安装的技能
 	selectedSkills := lib.SelectSkillsInteractive(skills)
 	if len(selectedSkills) == 0 {
-		fmt.Printf("\n%s No skills selected\n", lib.Yellow(lib.IconWarning))
+		fmt.Fprintf(textOut, "\n%s No skills selected\n", lib.Yellow(lib.IconWarning))
 		os.Exit(0)
 	}
 
-	// 加载配置
-	cfg, err := lib.LoadConfig()
+	lockPath := lib.LockfilePath(cfg)
+	lock, err := lib.LoadLockfile(lockPath)
 	if err != nil {
-		fmt.Printf("%s %v\n", lib.Red(lib.IconError), err)
+		fmt.Fprintf(textOut, "%s Error loading %s: %v\n", lib.Red(lib.IconError), lib.LockfileName, err)
 		os.Exit(1)
 	}
 
 	// 安装选中的技能
-	fmt.Println()
+	fmt.Fprintln(textOut)
 	success := 0
 	failed := 0
 	for _, skill := range selectedSkills {
 		err := lib.InstallSkill(skill, cfg)
 		if err != nil {
-			fmt.Printf("  %s %s: %v\n", lib.Red(lib.IconError), skill.Name, err)
+			fmt.Fprintf(textOut, "  %s %s: %v\n", lib.Red(lib.IconError), skill.Name, err)
 			failed++
-		} else {
-			fmt.Printf("  %s %s → %s/%s/\n", lib.Green(lib.IconSuccess), skill.Name, cfg.RepoPath, skill.Category)
-			success++
+			continue
+		}
+		fmt.Fprintf(textOut, "  %s %s → %s/%s/\n", lib.Green(lib.IconSuccess), skill.Name, cfg.RepoPath, skill.Category)
+		success++
+
+		// 记录来源到锁文件
+		entry, err := lib.NewLockEntry(source, parsed, skill, src)
+		if err != nil {
+			fmt.Fprintf(textOut, "  %s %s: failed to record origin: %v\n", lib.Yellow(lib.IconWarning), skill.Name, err)
+			continue
+		}
+		lock.Upsert(entry)
+	}
+
+	if success > 0 {
+		lib.RecordDistribution(cfg, lock)
+		if err := lib.SaveLockfile(lockPath, lock); err != nil {
+			fmt.Fprintf(textOut, "  %s Failed to write %s: %v\n", lib.Yellow(lib.IconWarning), lockPath, err)
 		}
 	}
 
-	fmt.Println()
+	fmt.Fprintln(textOut)
 	if success > 0 {
-		fmt.Printf("%s Installed %d skill(s). Run 'sk use' to distribute.\n\n", lib.Green(lib.IconSuccess), success)
+		fmt.Fprintf(textOut, "%s Installed %d skill(s). Run 'sk use' to distribute.\n\n", lib.Green(lib.IconSuccess), success)
 	}
 	if failed > 0 {
-		fmt.Printf("%s Failed to install %d skill(s)\n\n", lib.Red(lib.IconError), failed)
+		fmt.Fprintf(textOut, "%s Failed to install %d skill(s)\n\n", lib.Red(lib.IconError), failed)
 	}
 }
 
-func handleUse(args []string) {
-	if len(args) < 1 {
-		fmt.Println("Usage: sk use <module> [platform] [--global|--project] [--as <name>]")
-		os.Exit(1)
-	}
-
-	module := args[0]
-	platform := ""
-	scope := "global"
-	linkName := ""
+func handleUpdate(args []string) {
 	dryRun := false
-
-	for i := 1; i < len(args); i++ {
-		switch args[i] {
-		case "--global":
-			scope = "global"
-		case "--project":
-			scope = "project"
-		case "--as":
-			if i+1 < len(args) {
-				linkName = args[i+1]
-				i++
-			}
-		case "--dry-run":
+	offline := false
+	var names []string
+	for _, arg := range args {
+		if arg == "--dry-run" {
 			dryRun = true
-		default:
-			if platform == "" && !hasPrefix(args[i], "--") {
-				platform = args[i]
-			}
+		} else if arg == "--offline" {
+			offline = true
+		} else if !hasPrefix(arg, "--") {
+			names = append(names, arg)
 		}
 	}
 
-	cfg, err := lib.LoadConfig()
+	cfg, err := loadConfig()
 	if err != nil {
-		fmt.Printf("%s Error loading config: %v\n", lib.Red(lib.IconError), err)
+		fmt.Fprintf(textOut, "%s Error loading config: %v\n", lib.Red(lib.IconError), err)
 		os.Exit(1)
 	}
+	if offline {
+		cfg.Offline = true
+	}
 
-	mod, err := lib.FindModule(cfg, module)
+	lockPath := lib.LockfilePath(cfg)
+	lock, err := lib.LoadLockfile(lockPath)
 	if err != nil {
-		fmt.Printf("%s %v\n", lib.Red(lib.IconError), err)
+		fmt.Fprintf(textOut, "%s Error loading %s: %v\n", lib.Red(lib.IconError), lib.LockfileName, err)
 		os.Exit(1)
 	}
 
-	platforms := cfg.Platforms
-	if platform != "" {
-		if p, ok := cfg.Platforms[platform]; ok {
-			platforms = map[string]lib.Platform{platform: p}
-		} else {
-			fmt.Printf("%s Unknown platform: %s\n", lib.Red(lib.IconError), platform)
-			os.Exit(1)
+	// 确定需要更新的模块
+	var entries []*lib.LockEntry
+	if len(names) == 0 {
+		for i := range lock.Modules {
+			entries = append(entries, &lock.Modules[i])
+		}
+	} else {
+		for _, name := range names {
+			mod, err := lib.FindModule(cfg, name)
+			if err != nil {
+				fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), err)
+				os.Exit(1)
+			}
+			entry := lock.Find(mod.Category, filepath.Base(mod.Path))
+			if entry == nil {
+				fmt.Fprintf(textOut, "%s %s has no recorded origin in %s. Reinstall it with 'sk add'.\n",
+					lib.Red(lib.IconError), name, lib.LockfileName)
+				os.Exit(1)
+			}
+			entries = append(entries, entry)
 		}
 	}
 
-	if dryRun {
-		// 表格化输出
-		fmt.Printf("\n%s Preview: %s → %d platform(s)\n\n", lib.Blue(lib.IconInfo), module, len(platforms))
-		headers := []string{"Module", "Platform", "Target Path", "Action"}
-		var rows [][]string
+	if len(entries) == 0 {
+		fmt.Fprintf(textOut, "\n%s No modules recorded in %s.\n\n", lib.Yellow(lib.IconWarning), lockPath)
+		return
+	}
 
-		for name, p := range platforms {
-			ln := linkName
-			if ln == "" {
-				ln = mod.GetLinkName(name)
+	// 同一来源只获取一次
+	fetched := make(map[string]*lib.FetchedSource)
+	var unresolved []string
+	defer func() {
+		for _, src := range fetched {
+			if src != nil {
+				src.Cleanup()
 			}
-			baseDir := p.Global
-			if scope == "project" {
-				if p.Project == "" {
-					fmt.Printf("%s Project path not configured for platform: %s\n", lib.Red(lib.IconError), name)
-					os.Exit(1)
+		}
+	}()
+
+	fmt.Fprintln(textOut)
+	updated := 0
+	upToDate := 0
+	failed := 0
+	for _, entry := range entries {
+		key := entry.Type + ":" + entry.URL + "@" + entry.Ref
+		src, ok := fetched[key]
+		if !ok {
+			fmt.Fprintf(textOut, "%s Fetching %s...\n", lib.Blue(lib.IconInfo), lib.Redact(entry.URL))
+			src, err = lib.FetchSource(cfg, entry.ParsedSource())
+			if err != nil {
+				fmt.Fprintf(textOut, "  %s %v\n", lib.Red(lib.IconError), err)
+				if lib.IsOffline(err) {
+					unresolved = append(unresolved, err.(*lib.OfflineError).Source)
 				}
-				baseDir = p.Project
 			}
-			targetDir := lib.ResolvePath(baseDir, p.GetCategoryDir(mod.Category))
-			targetPath := targetDir + "/" + ln
+			fetched[key] = src
+		}
+		if src == nil {
+			fmt.Fprintf(textOut, "  %s %s: source unavailable\n", lib.Red(lib.IconError), entry.Name)
+			failed++
+			continue
+		}
 
-			action := "CREATE"
-			if lib.IsSymlink(targetPath) {
-				action = "UPDATE"
-			}
-			rows = append(rows, []string{module, name, targetPath, action})
+		skill, err := lib.FindLockedSkill(entry, src.Dir)
+		if err != nil {
+			fmt.Fprintf(textOut, "  %s %v\n", lib.Red(lib.IconError), err)
+			failed++
+			continue
 		}
 
-		lib.PrintTable(headers, rows)
-		fmt.Println()
-	} else {
-		fmt.Println()
-		for name, p := range platforms {
-			ln := linkName
-			if ln == "" {
-				ln = mod.GetLinkName(name)
-			}
-			baseDir := p.Global
-			if scope == "project" {
-				if p.Project == "" {
-					fmt.Printf("  %s Project path not configured for platform: %s\n", lib.Red(lib.IconError), name)
-					continue
-				}
-				baseDir = p.Project
-			}
-			targetDir := lib.ResolvePath(baseDir, p.GetCategoryDir(mod.Category))
-			targetPath := targetDir + "/" + ln
+		hash, err := lib.HashDir(skill.Path)
+		if err != nil {
+			fmt.Fprintf(textOut, "  %s %s: %v\n", lib.Red(lib.IconError), entry.Name, err)
+			failed++
+			continue
+		}
+		if hash == entry.Hash {
+			fmt.Fprintf(textOut, "  %s %s is up to date\n", lib.Green(lib.IconSuccess), entry.Name)
+			upToDate++
+			continue
+		}
 
-			err := lib.CreateSymlink(mod.Path, targetPath, scope == "project")
-			if err != nil {
-				fmt.Printf("  %s %s → %s: %v\n", lib.Red(lib.IconError), module, name, err)
-			} else {
-				fmt.Printf("  %s %s %s %s (%s)\n", lib.Green(lib.IconSuccess), module, lib.Cyan(lib.IconLink), targetPath, lib.Gray(name))
+		// 展示变更
+		installedDir := filepath.Join(cfg.RepoPath, entry.Category, entry.Name)
+		changes, err := lib.DiffDirs(installedDir, skill.Path)
+		if err != nil {
+			fmt.Fprintf(textOut, "  %s %s: %v\n", lib.Red(lib.IconError), entry.Name, err)
+			failed++
+			continue
+		}
+		to := src.Commit
+		if entry.Type == "archive" {
+			to = src.SHA256
+		}
+		fmt.Fprintf(textOut, "  %s %s %s\n", lib.Cyan(lib.IconArrow), lib.White(entry.Name), lib.Gray(shortRevision(entry)+" → "+shortCommit(to)))
+		for _, c := range changes {
+			switch c.Kind {
+			case "added":
+				fmt.Fprintf(textOut, "      %s %s\n", lib.Green("+"), c.Path)
+			case "removed":
+				fmt.Fprintf(textOut, "      %s %s\n", lib.Red("-"), c.Path)
+			default:
+				fmt.Fprintf(textOut, "      %s %s\n", lib.Yellow("~"), c.Path)
 			}
 		}
-		fmt.Println()
-	}
-}
 
-func handleList(args []string) {
-	cfg, err := lib.LoadConfig()
-	if err != nil {
-		fmt.Printf("%s Error loading config: %v\n", lib.Red(lib.IconError), err)
-		os.Exit(1)
-	}
+		if dryRun {
+			updated++
+			continue
+		}
 
-	modules, err := lib.ListModules(cfg)
-	if err != nil {
-		fmt.Printf("%s Error listing modules: %v\n", lib.Red(lib.IconError), err)
-		os.Exit(1)
+		if err := lib.UpdateSkill(skill, cfg); err != nil {
+			fmt.Fprintf(textOut, "  %s %s: %v\n", lib.Red(lib.IconError), entry.Name, err)
+			failed++
+			continue
+		}
+
+		entry.Commit = src.Commit
+		entry.SHA256 = src.SHA256
+		entry.Hash = hash
+		entry.InstalledAt = time.Now().UTC().Truncate(time.Second)
+		updated++
 	}
 
-	if len(modules) == 0 {
-		fmt.Printf("\n%s No modules found in ~/.config/agent/\n", lib.Yellow(lib.IconWarning))
-		fmt.Println("  Run 'sk init' to initialize the repository.")
-		fmt.Println()
+	fmt.Fprintln(textOut)
+	printUnresolved(unresolved)
+	if dryRun {
+		fmt.Fprintf(textOut, "%s %d module(s) can be updated, %d up to date\n\n", lib.Blue(lib.IconInfo), updated, upToDate)
+		if failed > 0 {
+			os.Exit(1)
+		}
 		return
 	}
 
-	fmt.Printf("\n%s Modules:\n\n", lib.Blue(lib.IconFolder))
-	for _, mod := range modules {
-		status := lib.GetLinkStatus(cfg, mod)
-		fmt.Printf("  %s %s %s\n", lib.Cyan(lib.IconArrow), lib.White(mod.Name), lib.Gray("("+mod.Category+")"))
-		if len(status) > 0 {
-			for i, s := range status {
-				prefix := "  │   ├──"
-				if i == len(status)-1 {
-					prefix = "  │   └──"
-				}
-				fmt.Printf("%s %s\n", lib.Gray(prefix), s)
-			}
-		} else {
-			fmt.Printf("  %s %s\n", lib.Gray("│   └──"), lib.Gray("(not linked)"))
+	if updated > 0 {
+		lib.RecordDistribution(cfg, lock)
+		if err := lib.SaveLockfile(lockPath, lock); err != nil {
+			fmt.Fprintf(textOut, "%s Failed to write %s: %v\n", lib.Yellow(lib.IconWarning), lockPath, err)
 		}
 	}
-	fmt.Println()
-}
-
-func handlePlatforms(args []string) {
-	cfg, err := lib.LoadConfig()
-	if err != nil {
-		fmt.Printf("%s Error loading config: %v\n", lib.Red(lib.IconError), err)
+	fmt.Fprintf(textOut, "  %s: %d  %s: %d  %s: %d\n\n",
+		lib.Green("Updated"), updated, lib.Gray("Up to date"), upToDate, lib.Red("Failed"), failed)
+	if failed > 0 {
 		os.Exit(1)
 	}
+}
 
-	fmt.Printf("\n%s Registered Platforms (%d):\n\n", lib.Blue(lib.IconInfo), len(cfg.Platforms))
-	for key, p := range cfg.Platforms {
-		fmt.Printf("  %s %s %s\n", lib.Cyan(lib.IconArrow), lib.White(p.Name), lib.Gray("("+key+")"))
-		fmt.Printf("      Project: %s\n", lib.Gray(p.Project+p.SkillDir+"/"))
-		fmt.Printf("      Global:  %s\n", lib.Gray(p.Global+p.SkillDir+"/"))
-		fmt.Println()
+func handleRestore(args []string) {
+	noLink := false
+	offline := false
+	lockArg := ""
+	for _, arg := range args {
+		if arg == "--no-link" {
+			noLink = true
+		} else if arg == "--offline" {
+			offline = true
+		} else if !hasPrefix(arg, "--") && lockArg == "" {
+			lockArg = arg
+		}
 	}
-}
 
-func handleInfo(args []string) {
-	if len(args) < 1 {
-		fmt.Println("Usage: sk info <module>")
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(textOut, "%s Error loading config: %v\n", lib.Red(lib.IconError), err)
 		os.Exit(1)
 	}
 
-	cfg, err := lib.LoadConfig()
-	if err != nil {
-		fmt.Printf("%s Error loading config: %v\n", lib.Red(lib.IconError), err)
+	repoLockPath := lib.LockfilePath(cfg)
+	lockPath := repoLockPath
+	if lockArg != "" {
+		lockPath = lib.ResolvePath(lockArg)
+	}
+	if _, err := os.Stat(lockPath); err != nil {
+		fmt.Fprintf(textOut, "%s Lockfile not found: %s\n", lib.Red(lib.IconError), lockPath)
 		os.Exit(1)
 	}
+	if offline {
+		cfg.Offline = true
+	}
+	// 优先使用随锁文件分发的归档
+	cfg.ArchiveDirs = append([]string{filepath.Join(filepath.Dir(lockPath), lib.ArchiveDirName)}, cfg.ArchiveDirs...)
 
-	mod, err := lib.FindModule(cfg, args[0])
+	lock, err := lib.LoadLockfile(lockPath)
 	if err != nil {
-		fmt.Printf("%s %v\n", lib.Red(lib.IconError), err)
+		fmt.Fprintf(textOut, "%s Error loading %s: %v\n", lib.Red(lib.IconError), lockPath, err)
 		os.Exit(1)
 	}
+	if len(lock.Modules) == 0 {
+		fmt.Fprintf(textOut, "\n%s No modules recorded in %s.\n\n", lib.Yellow(lib.IconWarning), lockPath)
+		return
+	}
+
+	fmt.Fprintf(textOut, "\n%s Restoring %d module(s) from %s\n\n", lib.Blue(lib.IconInfo), len(lock.Modules), lockPath)
+
+	// 同一来源、同一提交只获取一次
+	fetched := make(map[string]*lib.FetchedSource)
+	var unresolved []string
+	defer func() {
+		for _, src := range fetched {
+			if src != nil {
+				src.Cleanup()
+			}
+		}
+	}()
+
+	var restored []*lib.LockEntry
+	failed := 0
+	for i := range lock.Modules {
+		entry := &lock.Modules[i]
+
+		// 已安装且内容一致，无需获取
+		if lib.IsModuleIntact(cfg, entry) {
+			if entry.Link != nil {
+				dir := filepath.Join(cfg.RepoPath, entry.Category, entry.Name)
+				if err := lib.ApplyLinkConfig(dir, entry.Link); err != nil {
+					fmt.Fprintf(textOut, "  %s %s: %v\n", lib.Red(lib.IconError), entry.Name, err)
+					failed++
+					continue
+				}
+			}
+			fmt.Fprintf(textOut, "  %s %s %s\n", lib.Green(lib.IconSuccess), entry.Name, lib.Gray("(unchanged)"))
+			restored = append(restored, entry)
+			continue
+		}
+
+		key := entry.Type + ":" + entry.URL + "@" + entry.PinnedSource().Ref
+		src, ok := fetched[key]
+		if !ok {
+			fmt.Fprintf(textOut, "%s Fetching %s...\n", lib.Blue(lib.IconInfo), lib.Redact(entry.URL))
+			src, err = lib.FetchSource(cfg, entry.PinnedSource())
+			if err != nil {
+				fmt.Fprintf(textOut, "  %s %v\n", lib.Red(lib.IconError), err)
+				if lib.IsOffline(err) {
+					unresolved = append(unresolved, err.(*lib.OfflineError).Source)
+				}
+			}
+			fetched[key] = src
+		}
+		if src == nil {
+			fmt.Fprintf(textOut, "  %s %s: source unavailable\n", lib.Red(lib.IconError), entry.Name)
+			failed++
+			continue
+		}
+
+		action, err := lib.RestoreModule(cfg, entry, src.Dir)
+		if err != nil {
+			fmt.Fprintf(textOut, "  %s %v\n", lib.Red(lib.IconError), err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(textOut, "  %s %s %s\n", lib.Green(lib.IconSuccess), entry.Name, lib.Gray("("+action+")"))
+		restored = append(restored, entry)
+	}
+
+	// 恢复分发：默认平台 + 链接名
+	if !noLink && len(restored) > 0 {
+		platforms := make(map[string]lib.Platform)
+		for _, key := range lock.DefaultPlatforms {
+			if p, ok := cfg.Platforms[key]; ok {
+				platforms[key] = p
+			} else {
+				fmt.Fprintf(textOut, "  %s Unknown platform in lockfile: %s\n", lib.Yellow(lib.IconWarning), key)
+			}
+		}
+
+		if len(lock.DefaultPlatforms) > 0 && !equalStrings(cfg.DefaultPlatforms, lock.DefaultPlatforms) {
+			cfg.DefaultPlatforms = lock.DefaultPlatforms
+			if err := lib.SaveConfig(cfg); err != nil {
+				fmt.Fprintf(textOut, "  %s Failed to save default platforms: %v\n", lib.Yellow(lib.IconWarning), err)
+			} else {
+				fmt.Fprintf(textOut, "  %s Default platforms set to %s (from lockfile)\n", lib.Blue(lib.IconInfo), strings.Join(lock.DefaultPlatforms, ", "))
+			}
+		}
+
+		if len(platforms) > 0 {
+			fmt.Fprintf(textOut, "\n%s Linking to %d platform(s)...\n\n", lib.Blue(lib.IconInfo), len(platforms))
+		}
+		for _, entry := range restored {
+			mod, err := lib.FindModule(cfg, entry.Name)
+			if err != nil {
+				fmt.Fprintf(textOut, "  %s %v\n", lib.Red(lib.IconError), err)
+				failed++
+				continue
+			}
+			for platKey, p := range platforms {
+				targetPath := lib.ModuleTarget(p, platKey, mod, lib.ScopeGlobal)
+				if targetPath == "" {
+					fmt.Fprintf(textOut, "  %s Global path not configured for platform: %s\n", lib.Yellow(lib.IconWarning), platKey)
+					continue
+				}
+
+				if err := lib.Distribute(mod.Path, targetPath, p.GetLinkMode()); err != nil {
+					fmt.Fprintf(textOut, "  %s %s → %s: %v\n", lib.Red(lib.IconError), mod.Name, platKey, err)
+					failed++
+				} else {
+					fmt.Fprintf(textOut, "  %s %s %s %s\n", lib.Green(lib.IconSuccess), mod.Name, lib.Cyan(lib.IconLink), p.Name)
+				}
+			}
+		}
+	}
+
+	// 外部锁文件恢复后，合并到仓库锁文件
+	if lockPath != repoLockPath && len(restored) > 0 {
+		repoLock, err := lib.LoadLockfile(repoLockPath)
+		if err == nil {
+			for _, entry := range restored {
+				repoLock.Upsert(*entry)
+			}
+			lib.RecordDistribution(cfg, repoLock)
+			err = lib.SaveLockfile(repoLockPath, repoLock)
+		}
+		if err != nil {
+			fmt.Fprintf(textOut, "  %s Failed to update %s: %v\n", lib.Yellow(lib.IconWarning), repoLockPath, err)
+		}
+	}
+
+	fmt.Fprintln(textOut)
+	printUnresolved(unresolved)
+	fmt.Fprintf(textOut, "  %s: %d  %s: %d\n\n", lib.Green("Restored"), len(restored), lib.Red("Failed"), failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// installPlan 计算清单模块到项目目录的链接
+// 清单中的链接名只作用于本项目，不写入仓库中模块的 skillkit.toml
+func installPlan(cfg *lib.Config, manifest *lib.Manifest, modules []*lib.Module, platKeys []string) *lib.Plan {
+	links := manifest.LinkNames()
+	plan := &lib.Plan{}
+	for _, mod := range modules {
+		// 清单按仓库中的目录名记录模块（模块名可能已被 [link].default 替换）
+		link := links[filepath.Base(mod.Path)]
+		for _, key := range platKeys {
+			p := cfg.Platforms[key]
+			plan.Add(mod, key, p, p.GetBaseDir(lib.ScopeProject), link.LinkName(key))
+		}
+	}
+	return plan
+}
+
+func handleInstall(args []string) {
+	dryRun := false
+	atomic := false
+	relative := false
+	offline := false
+	for _, arg := range args {
+		switch arg {
+		case "--dry-run":
+			dryRun = true
+		case "--atomic":
+			atomic = true
+		case "--relative":
+			relative = true
+		case "--offline":
+			offline = true
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(textOut, "%s Error loading config: %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+	if offline {
+		cfg.Offline = true
+	}
+	if relative {
+		useRelativeLinks(cfg)
+	}
+
+	if !cfg.InProject() {
+		fmt.Fprintf(textOut, "%s No %s found in the current directory or its parents\n", lib.Red(lib.IconError), lib.ManifestName)
+		os.Exit(1)
+	}
+	manifest, err := lib.LoadManifest(filepath.Join(cfg.ProjectRoot, lib.ManifestName))
+	if os.IsNotExist(err) {
+		fmt.Fprintf(textOut, "%s No %s in project root %s\n", lib.Red(lib.IconError), lib.ManifestName, cfg.ProjectRoot)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+	platKeys, err := manifest.PlatformKeys(cfg)
+	if err != nil {
+		fmt.Fprintf(textOut, "%s %s: %v\n", lib.Red(lib.IconError), lib.ManifestName, err)
+		os.Exit(1)
+	}
+
+	// 区分仓库中已有和需要获取的模块
+	var present []*lib.Module
+	var missing []*lib.ManifestModule
+	failed := 0
+	for i := range manifest.Modules {
+		entry := &manifest.Modules[i]
+		mod, err := lib.FindModule(cfg, entry.Name)
+		switch {
+		case err == nil:
+			present = append(present, mod)
+		case entry.Source == "":
+			fmt.Fprintf(textOut, "%s %s is not in the repository and has no source in %s\n", lib.Red(lib.IconError), entry.Name, lib.ManifestName)
+			failed++
+		default:
+			missing = append(missing, entry)
+		}
+	}
+
+	if dryRun {
+		plan := installPlan(cfg, manifest, present, platKeys)
+		if structuredOutput() {
+			writeReport(planReport(cfg, plan, []string{lib.ScopeProject}))
+			return
+		}
+		fmt.Fprintf(textOut, "\n%s Preview: %d module(s) → %d platform(s)\n\n", lib.Blue(lib.IconInfo), len(manifest.Modules), len(platKeys))
+		printProjectRoot(cfg, []string{lib.ScopeProject})
+		for _, entry := range missing {
+			fmt.Fprintf(textOut, "  %s %s %s\n", lib.Yellow("+"), entry.Name, lib.Gray("(install from "+entry.Source+")"))
+		}
+		if len(missing) > 0 {
+			fmt.Fprintln(textOut)
+		}
+		printPlanTable(plan)
+		fmt.Fprintln(textOut)
+		return
+	}
+
+	// 获取缺失的模块，同一来源只获取一次
+	if len(missing) > 0 {
+		lockPath := lib.LockfilePath(cfg)
+		lock, err := lib.LoadLockfile(lockPath)
+		if err != nil {
+			fmt.Fprintf(textOut, "%s Error loading %s: %v\n", lib.Red(lib.IconError), lib.LockfileName, err)
+			os.Exit(1)
+		}
+
+		fmt.Fprintf(textOut, "\n%s Installing %d module(s)\n\n", lib.Blue(lib.IconInfo), len(missing))
+		fetched := make(map[string]*lib.FetchedSource)
+		var unresolved []string
+		defer func() {
+			for _, src := range fetched {
+				if src != nil {
+					src.Cleanup()
+				}
+			}
+		}()
+
+		installed := 0
+		for _, entry := range missing {
+			parsed := entry.ParsedSource(cfg.HostRegistry())
+			key := parsed.Type + ":" + parsed.URL + "@" + parsed.Ref
+			src, ok := fetched[key]
+			if !ok {
+				fmt.Fprintf(textOut, "%s Fetching %s...\n", lib.Blue(lib.IconInfo), lib.Redact(parsed.URL))
+				src, err = lib.FetchSource(cfg, parsed)
+				if err != nil {
+					fmt.Fprintf(textOut, "  %s %v\n", lib.Red(lib.IconError), err)
+					if lib.IsOffline(err) {
+						unresolved = append(unresolved, err.(*lib.OfflineError).Source)
+					}
+				}
+				fetched[key] = src
+			}
+			if src == nil {
+				fmt.Fprintf(textOut, "  %s %s: source unavailable\n", lib.Red(lib.IconError), entry.Name)
+				failed++
+				continue
+			}
+
+			skills, err := lib.DiscoverSkills(src.Dir, parsed.Subpath)
+			var skill *lib.DiscoveredSkill
+			if err == nil {
+				skill, err = lib.FindManifestSkill(skills, entry.Name)
+			}
+			if err == nil {
+				err = lib.InstallSkill(skill, cfg)
+			}
+			if err != nil {
+				fmt.Fprintf(textOut, "  %s %s: %v\n", lib.Red(lib.IconError), entry.Name, err)
+				failed++
+				continue
+			}
+			fmt.Fprintf(textOut, "  %s %s → %s/%s/\n", lib.Green(lib.IconSuccess), entry.Name, cfg.RepoPath, skill.Category)
+			installed++
+
+			if lockEntry, err := lib.NewLockEntry(entry.Source, parsed, skill, src); err == nil {
+				lock.Upsert(lockEntry)
+			} else {
+				fmt.Fprintf(textOut, "  %s %s: failed to record origin: %v\n", lib.Yellow(lib.IconWarning), entry.Name, err)
+			}
+			if mod, err := lib.FindModule(cfg, entry.Name); err == nil {
+				present = append(present, mod)
+			}
+		}
+		printUnresolved(unresolved)
+
+		if installed > 0 {
+			lib.RecordDistribution(cfg, lock)
+			if err := lib.SaveLockfile(lockPath, lock); err != nil {
+				fmt.Fprintf(textOut, "  %s Failed to write %s: %v\n", lib.Yellow(lib.IconWarning), lockPath, err)
+			}
+		}
+	}
+
+	plan := installPlan(cfg, manifest, present, platKeys)
+
+	if len(plan.Ops) > 0 {
+		fmt.Fprintf(textOut, "\n%s Linking %d module(s) into %s...\n\n", lib.Blue(lib.IconInfo), len(present), cfg.ProjectRoot)
+		_, linkFailed := printPlanResults(cfg, lib.ApplyPlan(plan, atomic))
+		failed += linkFailed
+	} else if len(platKeys) == 0 {
+		fmt.Fprintf(textOut, "\n%s No platform with a project path to link to\n", lib.Yellow(lib.IconWarning))
+	}
+
+	fmt.Fprintln(textOut)
+	if failed > 0 {
+		fmt.Fprintf(textOut, "%s %d problem(s) while installing %s\n\n", lib.Red(lib.IconError), failed, lib.ManifestName)
+		os.Exit(1)
+	}
+}
+
+func handleVendor(args []string) {
+	update := false
+	missingValue := false
+	var names []string
+	var platKeys []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--update":
+			update = true
+		case "--platform":
+			if i+1 < len(args) {
+				platKeys = append(platKeys, args[i+1])
+				i++
+			} else {
+				missingValue = true
+			}
+		default:
+			if !hasPrefix(args[i], "--") {
+				names = append(names, args[i])
+			}
+		}
+	}
+
+	if len(names) == 0 && !update || missingValue {
+		fmt.Fprintln(textOut, "Usage: sk vendor <module...> [--platform <platform>]...")
+		fmt.Fprintln(textOut, "       sk vendor --update [module...] [--platform <platform>]...")
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(textOut, "%s Error loading config: %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+
+	// 平台选择与项目清单一致：--platform > default_platforms > 全部，只保留配置了项目目录的平台
+	platKeys, err = (&lib.Manifest{Platforms: platKeys}).PlatformKeys(cfg)
+	if err != nil {
+		fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+	if len(platKeys) == 0 {
+		fmt.Fprintf(textOut, "%s No platform with a project path to vendor into\n", lib.Red(lib.IconError))
+		os.Exit(1)
+	}
+
+	fmt.Fprintln(textOut)
+	printProjectRoot(cfg, []string{lib.ScopeProject})
+
+	type vendorJob struct {
+		mod      *lib.Module
+		platform string
+		target   string
+	}
+	var jobs []vendorJob
+	failed := 0
+
+	if update {
+		copies, err := lib.FindVendored(cfg, platKeys)
+		if err != nil {
+			fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), err)
+			os.Exit(1)
+		}
+		wanted := make(map[string]bool)
+		for _, name := range names {
+			wanted[name] = true
+		}
+		for _, c := range copies {
+			if c.Err != nil {
+				fmt.Fprintf(textOut, "  %s %s: %v\n", lib.Red(lib.IconError), c.Target, c.Err)
+				failed++
+				continue
+			}
+			// 记录中是模块目录名，按名称筛选时也接受 [link].default 设置的名称
+			mod, err := lib.FindModuleInCategory(cfg, c.Marker.Category, c.Marker.Module)
+			if len(wanted) > 0 && !wanted[c.Marker.Module] && (err != nil || !wanted[mod.Name]) {
+				continue
+			}
+			if err != nil {
+				fmt.Fprintf(textOut, "  %s %s: %v\n", lib.Red(lib.IconError), c.Target, err)
+				failed++
+				continue
+			}
+			jobs = append(jobs, vendorJob{mod, c.Platform, c.Target})
+		}
+		if len(jobs) == 0 && failed == 0 {
+			fmt.Fprintf(textOut, "%s No vendored modules found.\n\n", lib.Yellow(lib.IconWarning))
+			return
+		}
+	} else {
+		for _, name := range names {
+			mod, err := lib.FindModule(cfg, name)
+			if err != nil {
+				fmt.Fprintf(textOut, "  %s %v\n", lib.Red(lib.IconError), err)
+				failed++
+				continue
+			}
+			for _, key := range platKeys {
+				jobs = append(jobs, vendorJob{mod, key, lib.ModuleTarget(cfg.Platforms[key], key, mod, lib.ScopeProject)})
+			}
+		}
+	}
+
+	for _, job := range jobs {
+		name := cfg.Platforms[job.platform].Name
+		action, err := lib.VendorModule(cfg, job.mod, job.target)
+		if err != nil {
+			fmt.Fprintf(textOut, "  %s %s → %s: %v\n", lib.Red(lib.IconError), job.mod.Name, job.platform, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(textOut, "  %s %s → %s %s\n", lib.Green(lib.IconSuccess), job.mod.Name, name, lib.Gray("("+action+")"))
+	}
+
+	fmt.Fprintln(textOut)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func handleSearch(args []string) {
+	refresh := false
+	offline := false
+	missingValue := false
+	var indexNames []string
+	var terms []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--refresh":
+			refresh = true
+		case "--offline":
+			offline = true
+		case "--index":
+			if i+1 < len(args) {
+				indexNames = append(indexNames, args[i+1])
+				i++
+			} else {
+				missingValue = true
+			}
+		default:
+			if !hasPrefix(args[i], "--") {
+				terms = append(terms, args[i])
+			}
+		}
+	}
+
+	if len(terms) == 0 || missingValue {
+		fmt.Fprintln(textOut, "Usage: sk search <query> [--index <name>]... [--refresh] [--offline]")
+		os.Exit(1)
+	}
+	query := strings.Join(terms, " ")
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(textOut, "%s Error loading config: %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+	if offline {
+		cfg.Offline = true
+	}
+	if len(cfg.Indexes) == 0 {
+		fmt.Fprintf(textOut, "%s No skill index configured. Add one to %s:\n\n", lib.Red(lib.IconError), cfg.ConfigPath)
+		fmt.Fprintln(textOut, "  [[indexes]]")
+		fmt.Fprintln(textOut, "  name = \"community\"")
+		fmt.Fprintln(textOut, "  url = \"https://example.com/skills.json\"")
+		fmt.Fprintln(textOut)
+		os.Exit(1)
+	}
+	indexes, err := lib.FindIndexes(cfg, indexNames)
+	if err != nil {
+		fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+
+	report := lib.SearchReport{SchemaVersion: lib.ReportSchemaVersion, Query: query, Results: []lib.SearchResult{}}
+	loaded := 0
+	for _, ic := range indexes {
+		index, stale, err := lib.LoadIndex(cfg, ic, refresh)
+		if index == nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("index %s unavailable: %v", ic.Name, err))
+			continue
+		}
+		if err != nil {
+			report.Warnings = append(report.Warnings, err.Error())
+		}
+		if stale {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("index %s could not be refreshed, using cached copy", ic.Name))
+		}
+		loaded++
+		report.Results = append(report.Results, lib.SearchIndex(index, ic.Name, query)...)
+	}
+	report.Results = lib.SortSearchResults(report.Results)
+
+	if structuredOutput() {
+		writeReport(report)
+		if loaded == 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintln(textOut)
+	for _, w := range report.Warnings {
+		fmt.Fprintf(textOut, "%s %s\n", lib.Yellow(lib.IconWarning), w)
+	}
+	if loaded == 0 {
+		fmt.Fprintf(textOut, "%s No index could be loaded\n\n", lib.Red(lib.IconError))
+		os.Exit(1)
+	}
+	if len(report.Results) == 0 {
+		fmt.Fprintf(textOut, "%s No skills match %q\n\n", lib.Yellow(lib.IconWarning), query)
+		return
+	}
+
+	// 非终端时只列出结果
+	if !lib.IsInteractive() {
+		for _, r := range report.Results {
+			fmt.Fprintf(textOut, "  %s %s %s\n", lib.White(r.Name), lib.Gray("("+r.Index+")"), r.Source)
+			if r.Description != "" {
+				fmt.Fprintf(textOut, "    %s\n", lib.Gray(r.Description))
+			}
+		}
+		fmt.Fprintln(textOut)
+		return
+	}
+
+	skills := make([]*lib.DiscoveredSkill, len(report.Results))
+	bySkill := make(map[*lib.DiscoveredSkill]lib.SearchResult)
+	for i, r := range report.Results {
+		skills[i] = r.DiscoveredSkill()
+		bySkill[skills[i]] = r
+	}
+	selected := lib.SelectSkills(skills, false)
+	if len(selected) == 0 {
+		fmt.Fprintf(textOut, "\n%s No skills selected\n", lib.Yellow(lib.IconWarning))
+		return
+	}
+
+	var results []lib.SearchResult
+	for _, skill := range selected {
+		results = append(results, bySkill[skill])
+	}
+	installSearchResults(cfg, results)
+}
+
+// installSearchResults 获取并安装选中的搜索结果，同一来源只获取一次
+func installSearchResults(cfg *lib.Config, results []lib.SearchResult) {
+	lockPath := lib.LockfilePath(cfg)
+	lock, err := lib.LoadLockfile(lockPath)
+	if err != nil {
+		fmt.Fprintf(textOut, "%s Error loading %s: %v\n", lib.Red(lib.IconError), lib.LockfileName, err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintln(textOut)
+	fetched := make(map[string]*lib.FetchedSource)
+	defer func() {
+		for _, src := range fetched {
+			if src != nil {
+				src.Cleanup()
+			}
+		}
+	}()
+
+	installed := 0
+	failed := 0
+	for _, r := range results {
+		parsed := lib.ParseSourceWith(r.Source, cfg.HostRegistry())
+		key := parsed.Type + ":" + parsed.URL + "@" + parsed.Ref
+		src, ok := fetched[key]
+		if !ok {
+			fmt.Fprintf(textOut, "%s Fetching %s...\n", lib.Blue(lib.IconInfo), lib.Redact(parsed.URL))
+			src, err = lib.FetchSource(cfg, parsed)
+			if err != nil {
+				fmt.Fprintf(textOut, "  %s %v\n", lib.Red(lib.IconError), err)
+			}
+			fetched[key] = src
+		}
+		if src == nil {
+			fmt.Fprintf(textOut, "  %s %s: source unavailable\n", lib.Red(lib.IconError), r.Name)
+			failed++
+			continue
+		}
+
+		skills, err := lib.DiscoverSkills(src.Dir, parsed.Subpath)
+		var skill *lib.DiscoveredSkill
+		if err == nil {
+			skill, err = lib.FindManifestSkill(skills, r.Name)
+		}
+		if err == nil {
+			err = lib.InstallSkill(skill, cfg)
+		}
+		if err != nil {
+			fmt.Fprintf(textOut, "  %s %s: %v\n", lib.Red(lib.IconError), r.Name, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(textOut, "  %s %s → %s/%s/\n", lib.Green(lib.IconSuccess), r.Name, cfg.RepoPath, skill.Category)
+		installed++
+
+		if entry, err := lib.NewLockEntry(r.Source, parsed, skill, src); err == nil {
+			lock.Upsert(entry)
+		} else {
+			fmt.Fprintf(textOut, "  %s %s: failed to record origin: %v\n", lib.Yellow(lib.IconWarning), r.Name, err)
+		}
+	}
+
+	if installed > 0 {
+		lib.RecordDistribution(cfg, lock)
+		if err := lib.SaveLockfile(lockPath, lock); err != nil {
+			fmt.Fprintf(textOut, "  %s Failed to write %s: %v\n", lib.Yellow(lib.IconWarning), lockPath, err)
+		}
+	}
+
+	fmt.Fprintln(textOut)
+	if installed > 0 {
+		fmt.Fprintf(textOut, "%s Installed %d skill(s). Run 'sk use' to distribute.\n\n", lib.Green(lib.IconSuccess), installed)
+	}
+	if failed > 0 {
+		fmt.Fprintf(textOut, "%s Failed to install %d skill(s)\n\n", lib.Red(lib.IconError), failed)
+		os.Exit(1)
+	}
+}
+
+func handleRegistry(args []string) {
+	usage := func() {
+		fmt.Fprintln(textOut, "Usage: sk registry build [-o <file>] [--source-base <source>]")
+		fmt.Fprintln(textOut, "       sk registry serve [--addr <host:port>] [--index <file>] [--source-base <source>]")
+		os.Exit(1)
+	}
+	if len(args) < 1 || (args[0] != "build" && args[0] != "serve") {
+		usage()
+	}
+
+	output := lib.RegistryIndexName
+	addr := "127.0.0.1:8080"
+	indexFile := ""
+	opts := lib.RegistryOptions{}
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "-o", "--output", "--addr", "--index", "--source-base":
+			if i+1 >= len(args) {
+				usage()
+			}
+		}
+		switch args[i] {
+		case "-o", "--output":
+			output = args[i+1]
+			i++
+		case "--addr":
+			addr = args[i+1]
+			i++
+		case "--index":
+			indexFile = args[i+1]
+			i++
+		case "--source-base":
+			opts.SourceBase = args[i+1]
+			i++
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(textOut, "%s Error loading config: %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+
+	build := func() ([]byte, *lib.SkillIndex, []lib.RegistrySkip, error) {
+		index, skipped, err := lib.BuildRegistry(cfg, opts)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		data, err := lib.MarshalIndex(index)
+		return data, index, skipped, err
+	}
+
+	// sk registry serve --index 每次请求读取该文件
+	if args[0] == "serve" && indexFile != "" {
+		load := func() ([]byte, error) { return os.ReadFile(indexFile) }
+		data, err := load()
+		if err == nil {
+			_, err = lib.ParseIndex(data)
+		}
+		if err != nil {
+			fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), err)
+			os.Exit(1)
+		}
+		fmt.Fprintln(textOut)
+		serveRegistry(addr, indexFile, load)
+		return
+	}
+
+	data, index, skipped, err := build()
+	if err != nil {
+		fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintln(textOut)
+	for _, skip := range skipped {
+		fmt.Fprintf(textOut, "  %s Skipped %s %s: %s\n", lib.Yellow(lib.IconWarning), skip.Category, skip.Name, skip.Reason)
+	}
+
+	if args[0] == "build" {
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(textOut, "  %s Wrote %d module(s) to %s\n\n", lib.Green(lib.IconSuccess), len(index.Skills), output)
+		return
+	}
+
+	// 每次请求从仓库重新生成，新增或更新的模块无需重启
+	serveRegistry(addr, fmt.Sprintf("%d module(s)", len(index.Skills)), func() ([]byte, error) {
+		data, _, _, err := build()
+		return data, err
+	})
+}
+
+// serveRegistry 启动索引 HTTP 服务
+func serveRegistry(addr, what string, load func() ([]byte, error)) {
+	fmt.Fprintf(textOut, "  %s Serving %s at http://%s/%s (Ctrl+C to stop)\n\n", lib.Green(lib.IconSuccess), what, addr, lib.RegistryIndexName)
+	if err := http.ListenAndServe(addr, lib.RegistryHandler(load)); err != nil {
+		fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+}
+
+func handleCache(args []string) {
+	sub := "list"
+	if len(args) > 0 {
+		sub = args[0]
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(textOut, "%s Error loading config: %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+
+	switch sub {
+	case "list":
+		entries, err := lib.ListCache(cfg)
+		if err != nil {
+			fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), err)
+			os.Exit(1)
+		}
+		if len(entries) == 0 {
+			fmt.Fprintf(textOut, "\n%s Cache is empty (%s)\n\n", lib.Blue(lib.IconInfo), lib.CacheRoot(cfg))
+			return
+		}
+
+		maxSize, _ := cfg.CacheMaxSize()
+		var total int64
+		headers := []string{"Repository", "Size", "Last Used"}
+		var rows [][]string
+		for _, e := range entries {
+			total += e.Size
+			rows = append(rows, []string{e.Key, lib.FormatSize(e.Size), e.LastUsed.Format("2006-01-02 15:04")})
+		}
+		fmt.Fprintf(textOut, "\n%s Git cache: %s\n\n", lib.Blue(lib.IconFolder), lib.CacheRoot(cfg))
+		lib.PrintTable(textOut, headers, rows)
+		fmt.Fprintf(textOut, "\n  Total: %s / %s\n\n", lib.FormatSize(total), lib.FormatSize(maxSize))
+
+	case "prune":
+		maxSize, err := cfg.CacheMaxSize()
+		if err != nil {
+			fmt.Fprintf(textOut, "%s Invalid cache.max_size: %v\n", lib.Red(lib.IconError), err)
+			os.Exit(1)
+		}
+		for i := 1; i < len(args); i++ {
+			switch args[i] {
+			case "--all":
+				maxSize = 0
+			case "--max-size":
+				if i+1 >= len(args) {
+					fmt.Fprintln(textOut, "Usage: sk cache prune [--max-size <size>] [--all]")
+					os.Exit(1)
+				}
+				maxSize, err = lib.ParseSize(args[i+1])
+				if err != nil {
+					fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), err)
+					os.Exit(1)
+				}
+				i++
+			}
+		}
+
+		removed, err := lib.PruneCache(cfg, maxSize)
+		fmt.Fprintln(textOut)
+		for _, e := range removed {
+			fmt.Fprintf(textOut, "  %s Removed %s (%s)\n", lib.Green(lib.IconSuccess), e.Key, lib.FormatSize(e.Size))
+		}
+		if err != nil {
+			fmt.Fprintf(textOut, "  %s %v\n\n", lib.Red(lib.IconError), err)
+			os.Exit(1)
+		}
+		if len(removed) == 0 {
+			fmt.Fprintf(textOut, "  %s Cache is within %s, nothing to prune\n", lib.Blue(lib.IconInfo), lib.FormatSize(maxSize))
+		}
+		fmt.Fprintln(textOut)
+
+	default:
+		fmt.Fprintln(textOut, "Usage: sk cache [list | prune [--max-size <size>] [--all]]")
+		os.Exit(1)
+	}
+}
+
+// printUnresolved 列出离线模式下无法解析的源
+func printUnresolved(sources []string) {
+	if len(sources) == 0 {
+		return
+	}
+	fmt.Fprintf(textOut, "%s %d source(s) cannot be resolved offline:\n", lib.Red(lib.IconError), len(sources))
+	for _, source := range sources {
+		fmt.Fprintf(textOut, "    %s\n", source)
+	}
+	fmt.Fprintf(textOut, "  Run once with network access to fill the cache, or ship %s/<commit>.tar.gz next to the lockfile.\n\n", lib.ArchiveDirName)
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// shortRevision 返回锁记录的简短版本：提交或归档的 SHA-256
+func shortRevision(entry *lib.LockEntry) string {
+	if entry.Type == "archive" {
+		return shortCommit(entry.SHA256)
+	}
+	return shortCommit(entry.Commit)
+}
+
+// shortCommit 返回提交 SHA 的短格式
+func shortCommit(commit string) string {
+	if commit == "" {
+		return "local"
+	}
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+func handleUse(args []string) {
+	scopes, args := lib.ParseScopes(args)
+	if len(args) < 1 {
+		fmt.Fprintln(textOut, "Usage: sk use <module> [platform] [--global|--project|--all-scopes] [--as <name>] [--relative] [--dry-run] [--atomic]")
+		os.Exit(1)
+	}
+
+	module := args[0]
+	platform := ""
+	linkName := ""
+	dryRun := false
+	atomic := false
+	relative := false
+
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--as":
+			if i+1 < len(args) {
+				linkName = args[i+1]
+				i++
+			}
+		case "--dry-run":
+			dryRun = true
+		case "--atomic":
+			atomic = true
+		case "--relative":
+			relative = true
+		default:
+			if platform == "" && !hasPrefix(args[i], "--") {
+				platform = args[i]
+			}
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(textOut, "%s Error loading config: %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+
+	mod, err := lib.FindModule(cfg, module)
+	if err != nil {
+		fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+
+	if relative {
+		useRelativeLinks(cfg)
+	}
+	platforms := cfg.Platforms
+	if platform != "" {
+		if p, ok := cfg.Platforms[platform]; ok {
+			platforms = map[string]lib.Platform{platform: p}
+		} else {
+			fmt.Fprintf(textOut, "%s Unknown platform: %s\n", lib.Red(lib.IconError), platform)
+			os.Exit(1)
+		}
+	}
+
+	// 先计算全部操作；未配置该作用域目录的平台跳过，明确指定的平台报错
+	plan := &lib.Plan{}
+	for _, scope := range scopes {
+		for _, name := range sortedPlatformKeys(platforms) {
+			p := platforms[name]
+			baseDir := p.GetBaseDir(scope)
+			if baseDir == "" {
+				if platform == "" {
+					continue
+				}
+				fmt.Fprintf(textOut, "%s %s path not configured for platform: %s\n", lib.Red(lib.IconError), scopeTitle(scope), name)
+				os.Exit(1)
+			}
+			ln := linkName
+			if ln == "" {
+				ln = p.LinkName(name, mod, scope)
+			}
+			plan.Add(mod, name, p, baseDir, ln)
+		}
+	}
+	if len(plan.Ops) == 0 {
+		fmt.Fprintf(textOut, "%s No platform has a %s path configured\n", lib.Red(lib.IconError), strings.Join(scopes, " or "))
+		os.Exit(1)
+	}
+
+	if dryRun {
+		if structuredOutput() {
+			writeReport(planReport(cfg, plan, scopes))
+			return
+		}
+		// 表格化输出
+		fmt.Fprintf(textOut, "\n%s Preview: %s → %d platform(s)\n\n", lib.Blue(lib.IconInfo), module, len(platforms))
+		printProjectRoot(cfg, scopes)
+		printPlanTable(plan)
+		fmt.Fprintln(textOut)
+		return
+	}
+
+	fmt.Fprintln(textOut)
+	if !cfg.InProject() {
+		printProjectRoot(cfg, scopes)
+	}
+	_, failed := printPlanResults(cfg, lib.ApplyPlan(plan, atomic))
+	fmt.Fprintln(textOut)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func handleList(args []string) {
+	scopes, _ := lib.ParseScopes(args)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(textOut, "%s Error loading config: %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+
+	modules, err := lib.ListModules(cfg)
+	if err != nil {
+		fmt.Fprintf(textOut, "%s Error listing modules: %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+
+	if structuredOutput() {
+		report := lib.ListReport{SchemaVersion: lib.ReportSchemaVersion, Modules: []lib.ModuleReport{}}
+		for _, mod := range modules {
+			report.Modules = append(report.Modules, lib.NewModuleReport(cfg, mod, scopes...))
+		}
+		writeReport(report)
+		return
+	}
+
+	if len(modules) == 0 {
+		fmt.Fprintf(textOut, "\n%s No modules found in ~/.config/agent/\n", lib.Yellow(lib.IconWarning))
+		fmt.Fprintln(textOut, "  Run 'sk init' to initialize the repository.")
+		fmt.Fprintln(textOut)
+		return
+	}
+
+	fmt.Fprintf(textOut, "\n%s Modules:\n\n", lib.Blue(lib.IconFolder))
+	for _, mod := range modules {
+		status := lib.GetLinkStatus(cfg, mod, scopes...)
+		fmt.Fprintf(textOut, "  %s %s %s\n", lib.Cyan(lib.IconArrow), lib.White(mod.Name), lib.Gray("("+mod.Category+")"))
+		if len(status) > 0 {
+			for i, s := range status {
+				prefix := "  │   ├──"
+				if i == len(status)-1 {
+					prefix = "  │   └──"
+				}
+				fmt.Fprintf(textOut, "%s %s\n", lib.Gray(prefix), s)
+			}
+		} else {
+			fmt.Fprintf(textOut, "  %s %s\n", lib.Gray("│   └──"), lib.Gray("(not linked)"))
+		}
+	}
+	fmt.Fprintln(textOut)
+}
+
+func handlePlatforms(args []string) {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(textOut, "%s Error loading config: %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+
+	if structuredOutput() {
+		writeReport(lib.NewPlatformsReport(cfg))
+		return
+	}
+
+	fmt.Fprintf(textOut, "\n%s Registered Platforms (%d):\n\n", lib.Blue(lib.IconInfo), len(cfg.Platforms))
+	for key, p := range cfg.Platforms {
+		fmt.Fprintf(textOut, "  %s %s %s\n", lib.Cyan(lib.IconArrow), lib.White(p.Name), lib.Gray("("+key+")"))
+		fmt.Fprintf(textOut, "      Project: %s\n", lib.Gray(p.Project+p.SkillDir+"/"))
+		fmt.Fprintf(textOut, "      Global:  %s\n", lib.Gray(p.Global+p.SkillDir+"/"))
+		fmt.Fprintln(textOut)
+	}
+}
+
+func handleInfo(args []string) {
+	scopes, args := lib.ParseScopes(args)
+	if len(args) < 1 {
+		fmt.Fprintln(textOut, "Usage: sk info <module> [--global|--project|--all-scopes]")
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(textOut, "%s Error loading config: %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+
+	mod, err := lib.FindModule(cfg, args[0])
+	if err != nil {
+		fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+
+	if structuredOutput() {
+		writeReport(lib.InfoReport{SchemaVersion: lib.ReportSchemaVersion, Module: lib.NewModuleReport(cfg, mod, scopes...)})
+		return
+	}
 
-	fmt.Println()
-	fmt.Printf("  %s %s\n", lib.Blue("Module:"), lib.White(mod.Name))
-	fmt.Printf("  %s %s\n", lib.Blue("Category:"), mod.Category)
-	fmt.Printf("  %s %s\n", lib.Blue("Path:"), mod.Path)
+	fmt.Fprintln(textOut)
+	fmt.Fprintf(textOut, "  %s %s\n", lib.Blue("Module:"), lib.White(mod.Name))
+	fmt.Fprintf(textOut, "  %s %s\n", lib.Blue("Category:"), mod.Category)
+	fmt.Fprintf(textOut, "  %s %s\n", lib.Blue("Path:"), mod.Path)
+	if mod.Description != "" {
+		fmt.Fprintf(textOut, "  %s %s\n", lib.Blue("Description:"), mod.Description)
+	}
+	if m := mod.Manifest; m != nil {
+		if m.Version != "" {
+			fmt.Fprintf(textOut, "  %s %s\n", lib.Blue("Version:"), m.Version)
+		}
+		if m.License != "" {
+			fmt.Fprintf(textOut, "  %s %s\n", lib.Blue("License:"), m.License)
+		}
+		if len(m.Tags) > 0 {
+			fmt.Fprintf(textOut, "  %s %s\n", lib.Blue("Tags:"), strings.Join(m.Tags, ", "))
+		}
+		if len(m.AllowedTools) > 0 {
+			fmt.Fprintf(textOut, "  %s %s\n", lib.Blue("Allowed tools:"), strings.Join(m.AllowedTools, ", "))
+		}
+	}
 
 	if len(mod.Aliases) > 0 {
-		fmt.Printf("  %s\n", lib.Blue("Aliases:"))
+		fmt.Fprintf(textOut, "  %s\n", lib.Blue("Aliases:"))
 		for platform, alias := range mod.Aliases {
-			fmt.Printf("    %s %s %s\n", platform, lib.Cyan(lib.IconLink), alias)
+			fmt.Fprintf(textOut, "    %s %s %s\n", platform, lib.Cyan(lib.IconLink), alias)
 		}
 	}
-	fmt.Println()
+
+	if status := lib.GetLinkStatus(cfg, mod, scopes...); len(status) > 0 {
+		fmt.Fprintf(textOut, "  %s\n", lib.Blue("Links:"))
+		for _, s := range status {
+			fmt.Fprintf(textOut, "    %s\n", s)
+		}
+	}
+	fmt.Fprintln(textOut)
 }
 
 func handleRemove(args []string) {
+	scopes, args := lib.ParseScopes(args)
 	if len(args) < 1 {
-		fmt.Println("Usage: sk remove <module> [platform]")
+		fmt.Fprintln(textOut, "Usage: sk remove <module> [platform] [--global|--project|--all-scopes]")
 		os.Exit(1)
 	}
 
-	cfg, err := lib.LoadConfig()
+	cfg, err := loadConfig()
 	if err != nil {
-		fmt.Printf("%s Error loading config: %v\n", lib.Red(lib.IconError), err)
+		fmt.Fprintf(textOut, "%s Error loading config: %v\n", lib.Red(lib.IconError), err)
 		os.Exit(1)
 	}
 
@@ -645,7 +2021,7 @@ func handleRemove(args []string) {
 
 	mod, err := lib.FindModule(cfg, module)
 	if err != nil {
-		fmt.Printf("%s %v\n", lib.Red(lib.IconError), err)
+		fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), err)
 		os.Exit(1)
 	}
 
@@ -654,163 +2030,553 @@ func handleRemove(args []string) {
 		if p, ok := cfg.Platforms[platform]; ok {
 			platforms = map[string]lib.Platform{platform: p}
 		} else {
-			fmt.Printf("%s Unknown platform: %s\n", lib.Red(lib.IconError), platform)
+			fmt.Fprintf(textOut, "%s Unknown platform: %s\n", lib.Red(lib.IconError), platform)
 			os.Exit(1)
 		}
 	}
 
-	fmt.Println()
-	for name, p := range platforms {
-		ln := mod.GetLinkName(name)
-		targetDir := lib.ResolvePath(p.Global, p.GetCategoryDir(mod.Category))
-		targetPath := targetDir + "/" + ln
+	fmt.Fprintln(textOut)
+	for _, scope := range scopes {
+		for _, name := range sortedPlatformKeys(platforms) {
+			targetPath := lib.ModuleTarget(platforms[name], name, mod, scope)
+			if targetPath == "" {
+				continue
+			}
 
-		err := lib.RemoveSymlink(targetPath)
-		if err != nil {
-			fmt.Printf("  %s %s from %s: %v\n", lib.Red(lib.IconError), module, name, err)
-		} else {
-			fmt.Printf("  %s Removed %s from %s\n", lib.Green(lib.IconSuccess), module, name)
+			label := name
+			if scope == lib.ScopeProject {
+				label += " (project)"
+			}
+			err := lib.Undistribute(targetPath)
+			if err != nil {
+				fmt.Fprintf(textOut, "  %s %s from %s: %v\n", lib.Red(lib.IconError), module, label, err)
+			} else {
+				fmt.Fprintf(textOut, "  %s Removed %s from %s\n", lib.Green(lib.IconSuccess), module, label)
+			}
 		}
 	}
-	fmt.Println()
+	fmt.Fprintln(textOut)
 }
 
 func handleSync(args []string) {
-	cfg, err := lib.LoadConfig()
+	cfg, err := loadConfig()
 	if err != nil {
-		fmt.Printf("%s Error loading config: %v\n", lib.Red(lib.IconError), err)
+		fmt.Fprintf(textOut, "%s Error loading config: %v\n", lib.Red(lib.IconError), err)
 		os.Exit(1)
 	}
 
 	modules, err := lib.ListModules(cfg)
 	if err != nil {
-		fmt.Printf("%s Error listing modules: %v\n", lib.Red(lib.IconError), err)
+		fmt.Fprintf(textOut, "%s Error listing modules: %v\n", lib.Red(lib.IconError), err)
 		os.Exit(1)
 	}
 
 	if len(modules) == 0 {
-		fmt.Printf("\n%s No modules to sync.\n\n", lib.Yellow(lib.IconWarning))
+		fmt.Fprintf(textOut, "\n%s No modules to sync.\n\n", lib.Yellow(lib.IconWarning))
 		return
 	}
 
+	scopes, args := lib.ParseScopes(args)
 	dryRun := false
+	atomic := false
+	relative := false
 	for _, arg := range args {
-		if arg == "--dry-run" {
+		switch arg {
+		case "--dry-run":
 			dryRun = true
+		case "--atomic":
+			atomic = true
+		case "--relative":
+			relative = true
 		}
 	}
 
-	totalLinks := len(modules) * len(cfg.Platforms)
+	if relative {
+		useRelativeLinks(cfg)
+	}
+	plan := lib.PlanLinks(modules, cfg.Platforms, scopes...)
 
 	if dryRun {
-		fmt.Printf("\n%s Preview: %d modules → %d platforms = %d symlinks\n\n",
-			lib.Blue(lib.IconInfo), len(modules), len(cfg.Platforms), totalLinks)
+		if structuredOutput() {
+			writeReport(planReport(cfg, plan, scopes))
+			return
+		}
+		fmt.Fprintf(textOut, "\n%s Preview: %d modules → %d platforms = %d symlinks\n\n",
+			lib.Blue(lib.IconInfo), len(modules), len(cfg.Platforms), len(plan.Ops))
+		printProjectRoot(cfg, scopes)
+		printPlanTable(plan)
+		fmt.Fprintln(textOut)
+		return
+	}
 
-		headers := []string{"Module", "Platform", "Target Path", "Action"}
-		var rows [][]string
+	fmt.Fprintf(textOut, "\n%s Syncing %d modules to %d platforms...\n\n",
+		lib.Blue(lib.IconInfo), len(modules), len(cfg.Platforms))
 
-		for _, mod := range modules {
-			for name, p := range cfg.Platforms {
-				ln := mod.GetLinkName(name)
-				targetDir := lib.ResolvePath(p.Global, p.GetCategoryDir(mod.Category))
-				targetPath := targetDir + "/" + ln
-
-				action := "CREATE"
-				if lib.IsSymlink(targetPath) {
-					action = "UPDATE"
-				}
-				rows = append(rows, []string{mod.Name, name, targetPath, action})
-			}
+	success, failed := printPlanResults(cfg, lib.ApplyPlan(plan, atomic))
+
+	fmt.Fprintln(textOut)
+	fmt.Fprintf(textOut, "  %s: %d  %s: %d\n\n", lib.Green("Success"), success, lib.Red("Failed"), failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func handleStatus(args []string) {
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(textOut, "%s Error loading config: %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+
+	modules, err := lib.ListModules(cfg)
+	if err != nil {
+		fmt.Fprintf(textOut, "%s Error listing modules: %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+
+	scopes, args := lib.ParseScopes(args)
+	check := false
+	for _, arg := range args {
+		if arg == "--check" {
+			check = true
 		}
+	}
 
-		lib.PrintTable(headers, rows)
-		fmt.Println()
-	} else {
-		fmt.Printf("\n%s Syncing %d modules to %d platforms...\n\n",
-			lib.Blue(lib.IconInfo), len(modules), len(cfg.Platforms))
+	report, scanErr := lib.NewStatusReport(cfg, modules, scopes...)
+	exitCode := report.ExitCode()
+	if scanErr != nil {
+		exitCode |= lib.ExitError
+	}
 
-		success := 0
-		failed := 0
+	if structuredOutput() {
+		if scanErr != nil {
+			fmt.Fprintf(textOut, "%s %v\n", lib.Yellow(lib.IconWarning), scanErr)
+		}
+		writeReport(report)
+		if check {
+			os.Exit(exitCode)
+		}
+		return
+	}
 
-		for _, mod := range modules {
-			for name, p := range cfg.Platforms {
-				ln := mod.GetLinkName(name)
-				targetDir := lib.ResolvePath(p.Global, p.GetCategoryDir(mod.Category))
-				targetPath := targetDir + "/" + ln
+	if scanErr != nil {
+		fmt.Fprintf(textOut, "\n%s %v\n", lib.Yellow(lib.IconWarning), scanErr)
+	}
 
-				err := lib.CreateSymlink(mod.Path, targetPath, false)
-				if err != nil {
-					fmt.Printf("  %s %s → %s: %v\n", lib.Red(lib.IconError), mod.Name, name, err)
-					failed++
+	if len(modules) == 0 && len(report.Orphans) == 0 {
+		fmt.Fprintf(textOut, "\n%s No modules found.\n\n", lib.Yellow(lib.IconWarning))
+		return
+	}
+
+	defaults := make(map[string]bool)
+	for _, key := range cfg.DefaultPlatforms {
+		defaults[key] = true
+	}
+
+	fmt.Fprintf(textOut, "\n%s Health Check\n\n", lib.Blue(lib.IconInfo))
+
+	vendorDrifted := false
+	for _, mr := range report.Modules {
+		for _, ls := range mr.Links {
+			where := ls.Platform
+			if ls.Scope == lib.ScopeProject {
+				where += " (project)"
+			}
+			kind := "copy"
+			if ls.Mode == lib.LinkModeVendor {
+				kind = "vendored copy"
+				if ls.State == lib.LinkStateOutdated {
+					vendorDrifted = true
+				}
+			}
+			switch ls.State {
+			case lib.LinkStateBroken:
+				if ls.Mode == lib.LinkModeSymlink || ls.Mode == lib.LinkModeRelative {
+					fmt.Fprintf(textOut, "  %s %s → %s: broken (points to %s)\n",
+						lib.Red(lib.IconError), mr.Name, where, ls.PointsTo)
+				} else if ls.Mode == lib.LinkModeVendor {
+					fmt.Fprintf(textOut, "  %s %s → %s: broken (vendored copy of another module)\n",
+						lib.Red(lib.IconError), mr.Name, where)
 				} else {
-					fmt.Printf("  %s %s %s %s\n", lib.Green(lib.IconSuccess), mod.Name, lib.Cyan(lib.IconLink), name)
-					success++
+					fmt.Fprintf(textOut, "  %s %s → %s: broken (copy of %s)\n",
+						lib.Red(lib.IconError), mr.Name, where, ls.PointsTo)
+				}
+			case lib.LinkStateBlocked:
+				fmt.Fprintf(textOut, "  %s %s → %s: blocked by real file/dir\n",
+					lib.Yellow(lib.IconWarning), mr.Name, where)
+			case lib.LinkStateModified:
+				fmt.Fprintf(textOut, "  %s %s → %s: %s modified locally\n",
+					lib.Yellow(lib.IconWarning), mr.Name, where, kind)
+			case lib.LinkStateOutdated:
+				fmt.Fprintf(textOut, "  %s %s → %s: %s outdated (source changed)\n",
+					lib.Yellow(lib.IconWarning), mr.Name, where, kind)
+			case lib.LinkStateMissing:
+				if check && defaults[ls.Platform] {
+					fmt.Fprintf(textOut, "  %s %s → %s: not linked (default platform)\n",
+						lib.Red(lib.IconError), mr.Name, where)
 				}
 			}
 		}
+	}
+
+	if len(report.Orphans) > 0 {
+		fmt.Fprintf(textOut, "\n  %s Orphaned links (%d):\n", lib.Yellow(lib.IconWarning), len(report.Orphans))
+		for _, orphan := range report.Orphans {
+			owner := "deleted module"
+			if orphan.ModuleName != "" {
+				owner = orphan.ModuleName
+			}
+			fmt.Fprintf(textOut, "    %s %s %s → %s\n", lib.Gray("○"), orphan.Target, lib.Gray("("+orphan.Scope+", "+owner+")"), orphan.PointsTo)
+		}
+	}
+
+	summary := report.Summary
+	fmt.Fprintln(textOut)
+	fmt.Fprintf(textOut, "  %s Healthy: %d  %s Broken: %d  %s Drifted: %d  %s Not linked: %d\n\n",
+		lib.Green(lib.IconSuccess), summary.Healthy,
+		lib.Red(lib.IconError), summary.Broken,
+		lib.Yellow(lib.IconWarning), summary.Drifted,
+		lib.Gray("○"), summary.Missing)
+
+	if summary.Broken > 0 {
+		fmt.Fprintf(textOut, "  %s Run 'sk sync' to fix broken links.\n\n", lib.Blue(lib.IconInfo))
+	}
+	if summary.Drifted > 0 {
+		fmt.Fprintf(textOut, "  %s Run 'sk sync' to refresh outdated copies; locally modified copies are left untouched.\n\n", lib.Blue(lib.IconInfo))
+	}
+	if vendorDrifted {
+		fmt.Fprintf(textOut, "  %s Run 'sk vendor --update' to refresh outdated vendored copies.\n\n", lib.Blue(lib.IconInfo))
+	}
+	if len(report.Orphans) > 0 {
+		fmt.Fprintf(textOut, "  %s Run 'sk doctor --fix' to delete or adopt orphaned links.\n\n", lib.Blue(lib.IconInfo))
+	}
 
-		fmt.Println()
-		fmt.Printf("  %s: %d  %s: %d\n\n", lib.Green("Success"), success, lib.Red("Failed"), failed)
+	if check {
+		if exitCode != 0 {
+			fmt.Fprintf(textOut, "  %s Check failed (exit code %d)\n\n", lib.Red(lib.IconError), exitCode)
+		}
+		os.Exit(exitCode)
 	}
 }
 
-func handleStatus(args []string) {
-	cfg, err := lib.LoadConfig()
+func handleDoctor(args []string) {
+	scopes, args := lib.ParseScopes(args, lib.AllScopes...)
+	fix := false
+	yes := false
+	for _, arg := range args {
+		switch arg {
+		case "--fix":
+			fix = true
+		case "--yes", "-y":
+			fix = true
+			yes = true
+		}
+	}
+
+	cfg, err := loadConfig()
 	if err != nil {
-		fmt.Printf("%s Error loading config: %v\n", lib.Red(lib.IconError), err)
+		fmt.Fprintf(textOut, "%s Error loading config: %v\n", lib.Red(lib.IconError), err)
 		os.Exit(1)
 	}
 
 	modules, err := lib.ListModules(cfg)
 	if err != nil {
-		fmt.Printf("%s Error listing modules: %v\n", lib.Red(lib.IconError), err)
+		fmt.Fprintf(textOut, "%s Error listing modules: %v\n", lib.Red(lib.IconError), err)
 		os.Exit(1)
 	}
 
-	if len(modules) == 0 {
-		fmt.Printf("\n%s No modules found.\n\n", lib.Yellow(lib.IconWarning))
+	issues, err := lib.Diagnose(cfg, modules, scopes...)
+	if err != nil {
+		fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+
+	if len(issues) == 0 {
+		fmt.Fprintf(textOut, "\n%s No broken, stale or orphaned links found.\n\n", lib.Green(lib.IconSuccess))
 		return
 	}
 
-	fmt.Printf("\n%s Health Check\n\n", lib.Blue(lib.IconInfo))
+	if !fix {
+		fmt.Fprintf(textOut, "\n%s Found %d link problem(s):\n\n", lib.Blue(lib.IconInfo), len(issues))
+		for _, issue := range issues {
+			printIssue(issue)
+		}
+		fmt.Fprintf(textOut, "\n  %s Run 'sk doctor --fix' to repair them, or 'sk doctor --yes' to apply the suggested fixes.\n\n", lib.Blue(lib.IconInfo))
+		return
+	}
 
-	healthy := 0
-	broken := 0
-	missing := 0
+	// 先逐个选择修复方式，再统一执行
+	choices := make([]string, len(issues))
+	for i, issue := range issues {
+		if yes {
+			choices[i] = issue.Fixes[0]
+			continue
+		}
+		options := make([]lib.SelectOption, 0, len(issue.Fixes)+1)
+		for _, f := range issue.Fixes {
+			options = append(options, lib.SelectOption{Key: f, Label: fixLabel(issue, f)})
+		}
+		options = append(options, lib.SelectOption{Key: "skip", Label: "Skip"})
+
+		title := fmt.Sprintf("(%d/%d) %s link %s → %s", i+1, len(issues), issue.Kind, issue.Target, issue.PointsTo)
+		result := lib.SelectMenu(title, options)
+		if result.Cancel {
+			lib.ClearScreen()
+			fmt.Fprintf(textOut, "\n%s Cancelled, nothing changed.\n\n", lib.Yellow(lib.IconWarning))
+			return
+		}
+		if !result.Back && result.Key != "skip" {
+			choices[i] = result.Key
+		}
+	}
+	if !yes {
+		lib.ClearScreen()
+	}
 
-	for _, mod := range modules {
-		for name, p := range cfg.Platforms {
-			ln := mod.GetLinkName(name)
-			targetDir := lib.ResolvePath(p.Global, p.GetCategoryDir(mod.Category))
-			targetPath := targetDir + "/" + ln
-
-			if lib.IsSymlink(targetPath) {
-				realPath, _ := lib.ReadSymlink(targetPath)
-				if realPath == mod.Path {
-					healthy++
-				} else {
-					fmt.Printf("  %s %s → %s: broken (points to %s)\n",
-						lib.Red(lib.IconError), mod.Name, name, realPath)
-					broken++
-				}
-			} else if _, err := os.Stat(targetPath); err == nil {
-				fmt.Printf("  %s %s → %s: blocked by real file/dir\n",
-					lib.Yellow(lib.IconWarning), mod.Name, name)
-				broken++
-			} else {
-				missing++
+	fmt.Fprintln(textOut)
+	fixed := 0
+	failed := 0
+	adopted := false
+	for i, issue := range issues {
+		if choices[i] == "" {
+			fmt.Fprintf(textOut, "  %s Skipped %s\n", lib.Gray("○"), issue.Target)
+			continue
+		}
+		if err := lib.ApplyFix(cfg, issue, choices[i]); err != nil {
+			fmt.Fprintf(textOut, "  %s %s: %v\n", lib.Red(lib.IconError), issue.Target, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(textOut, "  %s %s\n", lib.Green(lib.IconSuccess), fixLabel(issue, choices[i]))
+		fixed++
+		if choices[i] == lib.FixAdopt {
+			adopted = true
+		}
+	}
+
+	// 收编会修改 skillkit.toml，同步到锁文件
+	if adopted {
+		lockPath := lib.LockfilePath(cfg)
+		if lock, err := lib.LoadLockfile(lockPath); err == nil && len(lock.Modules) > 0 {
+			lib.RecordDistribution(cfg, lock)
+			if err := lib.SaveLockfile(lockPath, lock); err != nil {
+				fmt.Fprintf(textOut, "  %s Failed to update %s: %v\n", lib.Yellow(lib.IconWarning), lib.LockfileName, err)
+			}
+		}
+	}
+
+	fmt.Fprintln(textOut)
+	fmt.Fprintf(textOut, "  %s: %d  %s: %d\n\n", lib.Green("Fixed"), fixed, lib.Red("Failed"), failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// printIssue 输出单个链接问题
+func printIssue(issue lib.LinkIssue) {
+	switch issue.Kind {
+	case lib.IssueBroken:
+		fmt.Fprintf(textOut, "  %s broken  %s → %s: points to %s\n", lib.Red(lib.IconError), issue.Module.Name, issue.Platform, issue.PointsTo)
+	case lib.IssueStale:
+		fmt.Fprintf(textOut, "  %s stale   %s → %s: points to old path %s\n", lib.Yellow(lib.IconWarning), issue.Module.Name, issue.Platform, issue.PointsTo)
+	default:
+		owner := "deleted module"
+		if issue.Module != nil {
+			owner = issue.Module.Name
+		}
+		fmt.Fprintf(textOut, "  %s orphan  %s (%s) → %s\n", lib.Gray("○"), issue.Target, owner, issue.PointsTo)
+	}
+}
+
+// fixLabel 修复方式的说明
+func fixLabel(issue lib.LinkIssue, fix string) string {
+	switch fix {
+	case lib.FixRepoint:
+		return fmt.Sprintf("Re-point %s to %s", issue.Target, issue.Module.Path)
+	case lib.FixAdopt:
+		return fmt.Sprintf("Adopt %s as the %s link name of %s", filepath.Base(issue.Target), issue.Platform, issue.Module.Name)
+	}
+	return fmt.Sprintf("Delete %s", issue.Target)
+}
+
+func handleLint(args []string) {
+	report := lib.NewLintReport()
+
+	if len(args) == 0 {
+		cfg, err := loadConfig()
+		if err != nil {
+			fmt.Fprintf(textOut, "%s Error loading config: %v\n", lib.Red(lib.IconError), err)
+			os.Exit(1)
+		}
+		modules, err := lib.ListModules(cfg)
+		if err != nil {
+			fmt.Fprintf(textOut, "%s Error listing modules: %v\n", lib.Red(lib.IconError), err)
+			os.Exit(1)
+		}
+		for _, mod := range modules {
+			report.Add(lib.LintModule(mod.Path))
+		}
+	}
+
+	var cfg *lib.Config
+	for _, arg := range args {
+		// 已存在的目录按路径检查，否则在仓库中查找模块
+		if info, err := os.Stat(arg); err == nil && info.IsDir() {
+			dir, _ := filepath.Abs(arg)
+			skills, err := lib.DiscoverSkills(dir, "")
+			if err != nil {
+				fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), err)
+				os.Exit(1)
+			}
+			if len(skills) == 0 {
+				fmt.Fprintf(textOut, "%s No SKILL.md or AGENT.md found in %s\n", lib.Red(lib.IconError), arg)
+				os.Exit(1)
+			}
+			for _, skill := range skills {
+				report.Add(lib.LintModule(skill.Path))
+			}
+			continue
+		}
+
+		if cfg == nil {
+			var err error
+			if cfg, err = loadConfig(); err != nil {
+				fmt.Fprintf(textOut, "%s Error loading config: %v\n", lib.Red(lib.IconError), err)
+				os.Exit(1)
+			}
+		}
+		mod, err := lib.FindModule(cfg, arg)
+		if err != nil {
+			fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), err)
+			os.Exit(1)
+		}
+		report.Add(lib.LintModule(mod.Path))
+	}
+
+	if structuredOutput() {
+		writeReport(report)
+	} else {
+		printLintReport(report)
+	}
+	if report.Errors > 0 {
+		os.Exit(1)
+	}
+}
+
+// printLintReport 输出检查结果
+func printLintReport(report lib.LintReport) {
+	if len(report.Modules) == 0 {
+		fmt.Fprintf(textOut, "\n%s No modules found.\n\n", lib.Yellow(lib.IconWarning))
+		return
+	}
+
+	fmt.Fprintln(textOut)
+	for _, result := range report.Modules {
+		if len(result.Issues) == 0 {
+			fmt.Fprintf(textOut, "  %s %s\n", lib.Green(lib.IconSuccess), result.Name)
+			continue
+		}
+		fmt.Fprintf(textOut, "  %s %s %s\n", lib.White(result.Name), lib.Gray("→"), lib.Gray(result.Path))
+		for _, issue := range result.Issues {
+			icon := lib.Red(lib.IconError)
+			if issue.Severity == lib.LintWarning {
+				icon = lib.Yellow(lib.IconWarning)
+			}
+			where := issue.File
+			if where != "" && issue.Line > 0 {
+				where = fmt.Sprintf("%s:%d", where, issue.Line)
+			}
+			if where != "" {
+				where += ": "
+			}
+			fmt.Fprintf(textOut, "    %s %s%s %s\n", icon, where, issue.Message, lib.Gray("("+issue.Rule+")"))
+		}
+	}
+
+	fmt.Fprintf(textOut, "\n  %d module(s) checked, %d error(s), %d warning(s)\n\n", len(report.Modules), report.Errors, report.Warnings)
+}
+
+func handleNew(args []string) {
+	opts := lib.NewModuleOptions{}
+	use := false
+	missingValue := false
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--description", "-d", "--template", "--as", "--alias":
+			if i+1 >= len(args) {
+				missingValue = true
+				continue
+			}
+		}
+		switch args[i] {
+		case "--description", "-d":
+			opts.Description = args[i+1]
+			i++
+		case "--template":
+			opts.Template = args[i+1]
+			i++
+		case "--as":
+			opts.Link.Default = args[i+1]
+			i++
+		case "--alias":
+			platform, name, ok := strings.Cut(args[i+1], "=")
+			if !ok || platform == "" || name == "" {
+				fmt.Fprintf(textOut, "%s Invalid alias %q, expected <platform>=<name>\n", lib.Red(lib.IconError), args[i+1])
+				os.Exit(1)
+			}
+			if opts.Link.Overrides == nil {
+				opts.Link.Overrides = make(map[string]string)
+			}
+			opts.Link.Overrides[platform] = name
+			i++
+		case "--scripts":
+			opts.Scripts = true
+		case "--references":
+			opts.References = true
+		case "--use":
+			use = true
+		default:
+			if !hasPrefix(args[i], "--") {
+				positional = append(positional, args[i])
 			}
 		}
 	}
 
-	fmt.Println()
-	fmt.Printf("  %s Healthy: %d  %s Broken: %d  %s Not linked: %d\n\n",
-		lib.Green(lib.IconSuccess), healthy,
-		lib.Red(lib.IconError), broken,
-		lib.Gray("○"), missing)
+	if len(positional) != 2 || missingValue {
+		fmt.Fprintln(textOut, "Usage: sk new <skill|agent> <name> [--description <text>] [--template <name>]")
+		fmt.Fprintln(textOut, "              [--as <name>] [--alias <platform>=<name>]... [--scripts] [--references] [--use]")
+		os.Exit(1)
+	}
+	opts.Category, opts.Name = positional[0], positional[1]
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(textOut, "%s Error loading config: %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+	for platform := range opts.Link.Overrides {
+		if _, ok := cfg.Platforms[platform]; !ok {
+			fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), &lib.PlatformNotFoundError{Name: platform})
+			os.Exit(1)
+		}
+	}
+
+	mod, err := lib.CreateModule(cfg, opts)
+	if err != nil {
+		fmt.Fprintf(textOut, "%s %v\n", lib.Red(lib.IconError), err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintln(textOut)
+	fmt.Fprintf(textOut, "  %s Created %s %s at %s\n", lib.Green(lib.IconSuccess), opts.Category, lib.White(opts.Name), mod.Path)
+	if opts.Description == "" {
+		fmt.Fprintf(textOut, "  %s Edit the description in %s before sharing it\n", lib.Blue(lib.IconInfo), lib.SkillFileName(opts.Category))
+	}
+	fmt.Fprintln(textOut)
 
-	if broken > 0 {
-		fmt.Printf("  %s Run 'sk sync' to fix broken links.\n\n", lib.Blue(lib.IconInfo))
+	if use {
+		handleUse([]string{opts.Name})
 	}
 }
 
@@ -825,12 +2591,12 @@ func handleInit(args []string) {
 		repoPath + "/agent",
 	}
 
-	fmt.Println()
+	fmt.Fprintln(textOut)
 	for _, dir := range dirs {
 		if err := os.MkdirAll(dir, 0755); err != nil {
-			fmt.Printf("  %s Failed to create %s: %v\n", lib.Red(lib.IconError), dir, err)
+			fmt.Fprintf(textOut, "  %s Failed to create %s: %v\n", lib.Red(lib.IconError), dir, err)
 		} else {
-			fmt.Printf("  %s Created %s\n", lib.Green(lib.IconSuccess), dir)
+			fmt.Fprintf(textOut, "  %s Created %s\n", lib.Green(lib.IconSuccess), dir)
 		}
 	}
 
@@ -844,15 +2610,15 @@ func handleInit(args []string) {
 
 		if data, err := os.ReadFile(srcPath); err == nil {
 			if err := os.WriteFile(configPath, data, 0644); err == nil {
-				fmt.Printf("  %s Created %s\n", lib.Green(lib.IconSuccess), configPath)
+				fmt.Fprintf(textOut, "  %s Created %s\n", lib.Green(lib.IconSuccess), configPath)
 			}
 		}
 	} else {
-		fmt.Printf("  %s %s already exists\n", lib.Yellow(lib.IconWarning), configPath)
+		fmt.Fprintf(textOut, "  %s %s already exists\n", lib.Yellow(lib.IconWarning), configPath)
 	}
 
-	fmt.Println()
-	fmt.Printf("  %s Repository initialized at %s\n\n", lib.Green(lib.IconSuccess), repoPath)
+	fmt.Fprintln(textOut)
+	fmt.Fprintf(textOut, "  %s Repository initialized at %s\n\n", lib.Green(lib.IconSuccess), repoPath)
 }
 
 func hasPrefix(s, prefix string) bool {
@@ -5,6 +5,7 @@ go 1.24.0
 require (
 	github.com/pelletier/go-toml/v2 v2.1.1
 	golang.org/x/term v0.39.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require golang.org/x/sys v0.40.0 // indirect
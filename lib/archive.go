@@ -0,0 +1,341 @@
+package lib
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ArchiveDirName 随锁文件一起分发的源码归档目录名
+// 归档按提交命名: skillkit-archives/<commit>.tar.gz
+const ArchiveDirName = "skillkit-archives"
+
+// archiveExtensions 支持的归档扩展名
+var archiveExtensions = []string{".tar.gz", ".tgz", ".tar", ".zip"}
+
+// 归档来源的大小限制，防止超大下载和解压炸弹
+const (
+	MaxArchiveDownloadSize  = 100 << 20 // 下载的归档文件
+	MaxArchiveExtractedSize = 512 << 20 // 解压后的文件总大小
+	MaxArchiveEntries       = 10000     // 条目数
+)
+
+// archiveFetchTimeout 下载归档的超时
+const archiveFetchTimeout = 10 * time.Minute
+
+// archiveLimits 解压限制
+type archiveLimits struct {
+	extracted int64
+	entries   int
+}
+
+var defaultArchiveLimits = archiveLimits{extracted: MaxArchiveExtractedSize, entries: MaxArchiveEntries}
+
+// FindArchive 在归档目录中查找指定提交的归档
+func FindArchive(dirs []string, commit string) string {
+	if commit == "" {
+		return ""
+	}
+	for _, dir := range dirs {
+		for _, ext := range archiveExtensions {
+			path := filepath.Join(dir, commit+ext)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// isArchiveSource 检查源地址是否为归档：以归档扩展名结尾的 http(s) 地址或本地文件
+func isArchiveSource(input string) bool {
+	path := input
+	if strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://") {
+		path, _, _ = strings.Cut(path, "#")
+		path, _, _ = strings.Cut(path, "?")
+	} else if !isLocalPath(input) {
+		return false
+	} else if info, err := os.Stat(input); err == nil && info.IsDir() {
+		return false
+	}
+
+	path = strings.ToLower(path)
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchArchive 获取归档来源：下载或读取归档，校验 SHA-256 后解压到临时目录
+func fetchArchive(cfg *Config, parsed *ParsedSource) (*FetchedSource, error) {
+	path := parsed.LocalPath
+	if path == "" {
+		if cfg != nil && cfg.Offline {
+			return nil, &OfflineError{Source: Redact(parsed.URL)}
+		}
+		downloaded, err := downloadArchive(cfg, parsed.URL, MaxArchiveDownloadSize)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(downloaded)
+		path = downloaded
+	} else if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("path not found: %s", path)
+	}
+
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.SHA256 != "" && !strings.EqualFold(parsed.SHA256, sum) {
+		return nil, fmt.Errorf("checksum mismatch for %s: expected sha256 %s, got %s", Redact(parsed.URL), strings.ToLower(parsed.SHA256), sum)
+	}
+
+	tempDir, err := ExtractArchive(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FetchedSource{Dir: tempDir, SHA256: sum, tempDir: tempDir}, nil
+}
+
+// downloadArchive 把归档下载到临时文件（按主机附加凭据），超过 maxSize 时失败
+func downloadArchive(cfg *Config, url string, maxSize int64) (string, error) {
+	resp, err := httpGet(cfg, url, archiveFetchTimeout, "")
+	if err != nil {
+		return "", fmt.Errorf("download failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download failed: GET %s: %s", Redact(url), resp.Status)
+	}
+	if resp.ContentLength > maxSize {
+		return "", fmt.Errorf("archive is larger than %s: %s", FormatSize(maxSize), Redact(url))
+	}
+
+	f, err := os.CreateTemp("", "skillkit-archive-")
+	if err != nil {
+		return "", err
+	}
+	n, err := io.Copy(f, io.LimitReader(resp.Body, maxSize+1))
+	f.Close()
+	if err == nil && n > maxSize {
+		err = fmt.Errorf("archive is larger than %s: %s", FormatSize(maxSize), Redact(url))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// IsSHA256 判断字符串是否为 64 位十六进制的 SHA-256
+func IsSHA256(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// fileSHA256 计算文件的 SHA-256
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ExtractArchive 将 zip/tar/tar.gz 归档解压到临时目录（按文件内容识别格式）
+// 离线归档内的路径即源中的路径，应使用 git archive <commit> 生成（不加 --prefix）。
+func ExtractArchive(path string) (string, error) {
+	tempDir, err := os.MkdirTemp("", "skillkit-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+
+	if err := extractArchive(path, tempDir, defaultArchiveLimits); err != nil {
+		os.RemoveAll(tempDir)
+		return "", fmt.Errorf("failed to extract %s: %w", filepath.Base(path), err)
+	}
+	return tempDir, nil
+}
+
+func extractArchive(path, dest string, limits archiveLimits) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	magic, _ := br.Peek(512)
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")) || bytes.HasPrefix(magic, []byte("PK\x05\x06")):
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		return extractZip(f, info.Size(), dest, limits)
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		return extractTar(gz, dest, limits)
+	case len(magic) >= 262 && string(magic[257:262]) == "ustar":
+		return extractTar(br, dest, limits)
+	}
+	return fmt.Errorf("unsupported archive format (expected zip, tar or tar.gz)")
+}
+
+func extractTar(r io.Reader, dest string, limits archiveLimits) error {
+	ex := &extractor{dest: dest, limits: limits}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = ex.dir(hdr.Name)
+		case tar.TypeReg:
+			err = ex.file(hdr.Name, os.FileMode(hdr.Mode), tr)
+		default:
+			// 跳过软链接、硬链接、设备文件等，解压结果中不会有指向目录之外的链接
+			err = ex.count()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func extractZip(r io.ReaderAt, size int64, dest string, limits archiveLimits) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+
+	ex := &extractor{dest: dest, limits: limits}
+	for _, zf := range zr.File {
+		mode := zf.Mode()
+		switch {
+		case mode.IsDir():
+			err = ex.dir(zf.Name)
+		case mode.IsRegular():
+			if zf.UncompressedSize64 > uint64(limits.extracted) {
+				return fmt.Errorf("archive exceeds the %s extraction limit", FormatSize(limits.extracted))
+			}
+			var rc io.ReadCloser
+			if rc, err = zf.Open(); err == nil {
+				err = ex.file(zf.Name, mode, rc)
+				rc.Close()
+			}
+		default:
+			err = ex.count()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractor 解压时的路径检查和大小统计
+type extractor struct {
+	dest    string
+	limits  archiveLimits
+	entries int
+	written int64
+}
+
+func (ex *extractor) count() error {
+	ex.entries++
+	if ex.entries > ex.limits.entries {
+		return fmt.Errorf("archive has more than %d entries", ex.limits.entries)
+	}
+	return nil
+}
+
+func (ex *extractor) dir(name string) error {
+	if err := ex.count(); err != nil {
+		return err
+	}
+	target, err := safeJoin(ex.dest, name)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(target, 0755)
+}
+
+// file 写入普通文件，保留可执行权限，超过解压总大小限制时失败
+func (ex *extractor) file(name string, mode os.FileMode, r io.Reader) error {
+	if err := ex.count(); err != nil {
+		return err
+	}
+	target, err := safeJoin(ex.dest, name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	perm := os.FileMode(0644)
+	if mode&0111 != 0 {
+		perm = 0755
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	remaining := ex.limits.extracted - ex.written
+	n, err := io.Copy(out, io.LimitReader(r, remaining+1))
+	out.Close()
+	if err != nil {
+		return err
+	}
+	ex.written += n
+	if ex.written > ex.limits.extracted {
+		return fmt.Errorf("archive exceeds the %s extraction limit", FormatSize(ex.limits.extracted))
+	}
+	return nil
+}
+
+// safeJoin 拼接归档条目路径，拒绝绝对路径和跳出目标目录的条目
+func safeJoin(dest, name string) (string, error) {
+	if filepath.IsAbs(name) || strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("illegal absolute path in archive: %s", name)
+	}
+	target := filepath.Join(dest, name)
+	rel, err := filepath.Rel(dest, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("illegal path in archive: %s", name)
+	}
+	return target, nil
+}
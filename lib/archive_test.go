@@ -0,0 +1,332 @@
+package lib
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestTarGz 写入 tar.gz 归档，files 为 路径 -> 内容
+func writeTestTarGz(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("failed to write header: %v", err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+}
+
+// writeTestZip 写入 zip 归档，files 为 路径 -> 内容，以 / 结尾的路径为目录
+func writeTestZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("failed to write entry: %v", err)
+		}
+		w.Write([]byte(content))
+	}
+	zw.Close()
+}
+
+func TestExtractArchive(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "src.tar.gz")
+	writeTestTarGz(t, archive, map[string]string{
+		"skills/foo/SKILL.md": "---\nname: foo\n---\n",
+	})
+
+	dir, err := ExtractArchive(archive)
+	if err != nil {
+		t.Fatalf("ExtractArchive failed: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := os.Stat(filepath.Join(dir, "skills", "foo", "SKILL.md")); err != nil {
+		t.Errorf("expected extracted SKILL.md: %v", err)
+	}
+}
+
+func TestExtractArchiveRejectsPathTraversal(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "evil.tar.gz")
+	writeTestTarGz(t, archive, map[string]string{
+		"../../escaped.txt": "pwned",
+	})
+
+	if dir, err := ExtractArchive(archive); err == nil {
+		os.RemoveAll(dir)
+		t.Error("expected error for path traversal entry")
+	}
+}
+
+func TestExtractZip(t *testing.T) {
+	// 扩展名与内容不符时按内容识别格式
+	archive := filepath.Join(t.TempDir(), "skills.tar.gz")
+	writeTestZip(t, archive, map[string]string{
+		"pack/":                   "",
+		"pack/foo/SKILL.md":       "---\nname: foo\n---\n",
+		"pack/foo/scripts/run.sh": "#!/bin/sh\n",
+	})
+
+	dir, err := ExtractArchive(archive)
+	if err != nil {
+		t.Fatalf("ExtractArchive failed: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	skills, err := DiscoverSkills(dir, "")
+	if err != nil || len(skills) != 1 || skills[0].Name != "foo" {
+		t.Errorf("DiscoverSkills = %+v, %v", skills, err)
+	}
+}
+
+func TestExtractZipRejectsPathTraversal(t *testing.T) {
+	for _, name := range []string{"../escaped.txt", "a/../../escaped.txt", "/abs.txt"} {
+		archive := filepath.Join(t.TempDir(), "evil.zip")
+		writeTestZip(t, archive, map[string]string{name: "pwned"})
+		if dir, err := ExtractArchive(archive); err == nil {
+			os.RemoveAll(dir)
+			t.Errorf("expected error for entry %s", name)
+		}
+	}
+}
+
+func TestExtractArchiveSkipsSymlinks(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "skill/SKILL.md", Mode: 0755, Size: 2, Typeflag: tar.TypeReg})
+	tw.Write([]byte("ok"))
+	tw.WriteHeader(&tar.Header{Name: "skill/passwd", Linkname: "/etc/passwd", Typeflag: tar.TypeSymlink})
+	tw.WriteHeader(&tar.Header{Name: "skill/up", Linkname: "../..", Typeflag: tar.TypeSymlink})
+	tw.Close()
+
+	archive := filepath.Join(t.TempDir(), "links.tar")
+	os.WriteFile(archive, buf.Bytes(), 0644)
+
+	dir, err := ExtractArchive(archive)
+	if err != nil {
+		t.Fatalf("ExtractArchive failed: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"passwd", "up"} {
+		if _, err := os.Lstat(filepath.Join(dir, "skill", name)); !os.IsNotExist(err) {
+			t.Errorf("symlink %s should have been skipped", name)
+		}
+	}
+	info, err := os.Stat(filepath.Join(dir, "skill", "SKILL.md"))
+	if err != nil || info.Mode().Perm()&0100 == 0 {
+		t.Errorf("expected executable SKILL.md, got %v, %v", info, err)
+	}
+}
+
+func TestExtractArchiveLimits(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "big.zip")
+	writeTestZip(t, archive, map[string]string{
+		"a.txt": strings.Repeat("a", 600),
+		"b.txt": strings.Repeat("b", 600),
+	})
+
+	tests := []struct {
+		limits archiveLimits
+		ok     bool
+	}{
+		{archiveLimits{extracted: 2000, entries: 10}, true},
+		{archiveLimits{extracted: 1000, entries: 10}, false},
+		{archiveLimits{extracted: 500, entries: 10}, false},
+		{archiveLimits{extracted: 2000, entries: 1}, false},
+	}
+	for _, tt := range tests {
+		err := extractArchive(archive, t.TempDir(), tt.limits)
+		if (err == nil) != tt.ok {
+			t.Errorf("limits %+v: err = %v", tt.limits, err)
+		}
+	}
+
+	notArchive := filepath.Join(t.TempDir(), "skills.zip")
+	os.WriteFile(notArchive, []byte("not an archive"), 0644)
+	if err := extractArchive(notArchive, t.TempDir(), defaultArchiveLimits); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestParseSourceArchive(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "skills.tgz")
+	writeTestTarGz(t, local, map[string]string{"SKILL.md": "x"})
+
+	tests := []struct {
+		input string
+		local bool
+	}{
+		{"https://example.com/releases/skills.zip", false},
+		{"https://example.com/skills.tar.gz?token=abc#frag", false},
+		{"http://example.com/SKILLS.TAR", false},
+		{local, true},
+	}
+	for _, tt := range tests {
+		parsed := ParseSource(tt.input)
+		if parsed.Type != "archive" || (parsed.LocalPath != "") != tt.local {
+			t.Errorf("ParseSource(%s) = %+v", tt.input, parsed)
+		}
+	}
+
+	for _, input := range []string{"https://github.com/owner/repo", "owner/skills.zip", t.TempDir()} {
+		if parsed := ParseSource(input); parsed.Type == "archive" {
+			t.Errorf("ParseSource(%s) should not be an archive", input)
+		}
+	}
+	if parsed := ParseSource("https://example.com/skills.zip@v1"); parsed.Type != "archive" || parsed.Ref != "" {
+		t.Errorf("archive should ignore @ref: %+v", parsed)
+	}
+}
+
+func TestFetchSourceArchive(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "skills.zip")
+	writeTestZip(t, archive, map[string]string{"skills/foo/SKILL.md": "---\nname: foo\n---\n"})
+	data, _ := os.ReadFile(archive)
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/skills.zip" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	cfg := &Config{RepoPath: t.TempDir()}
+	parsed := ParseSource(server.URL + "/skills.zip")
+	parsed.SHA256 = strings.ToUpper(checksum)
+	src, err := FetchSource(cfg, parsed)
+	if err != nil {
+		t.Fatalf("FetchSource failed: %v", err)
+	}
+	defer src.Cleanup()
+	if src.SHA256 != checksum || src.Commit != "" {
+		t.Errorf("unexpected fetched source: %+v", src)
+	}
+	if _, err := os.Stat(filepath.Join(src.Dir, "skills", "foo", "SKILL.md")); err != nil {
+		t.Errorf("expected extracted SKILL.md: %v", err)
+	}
+
+	parsed.SHA256 = strings.Repeat("0", 64)
+	if _, err := FetchSource(cfg, parsed); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("expected checksum mismatch, got %v", err)
+	}
+	if _, err := FetchSource(cfg, ParseSource(server.URL+"/missing.zip")); err == nil {
+		t.Error("expected error for missing archive")
+	}
+
+	// 本地归档不需要下载，离线时也可用
+	cfg.Offline = true
+	if _, err := FetchSource(cfg, ParseSource(server.URL+"/skills.zip")); !IsOffline(err) {
+		t.Errorf("expected OfflineError, got %v", err)
+	}
+	local, err := FetchSource(cfg, &ParsedSource{Type: "archive", URL: archive, LocalPath: archive, SHA256: checksum})
+	if err != nil {
+		t.Fatalf("FetchSource(local archive) failed: %v", err)
+	}
+	local.Cleanup()
+	if requests != 3 {
+		t.Errorf("expected 3 requests, got %d", requests)
+	}
+}
+
+func TestDownloadArchiveSizeLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush() // 不设置 Content-Length，按实际读取量限制
+		w.Write(bytes.Repeat([]byte("x"), 2048))
+	}))
+	defer server.Close()
+
+	if path, err := downloadArchive(nil, server.URL+"/a.zip", 1024); err == nil {
+		os.Remove(path)
+		t.Error("expected error for oversized download")
+	}
+	path, err := downloadArchive(nil, server.URL+"/a.zip", 4096)
+	if err != nil {
+		t.Fatalf("downloadArchive failed: %v", err)
+	}
+	os.Remove(path)
+}
+
+func TestFetchSourceOffline(t *testing.T) {
+	cfg := &Config{RepoPath: t.TempDir(), Offline: true}
+	commit := "0123456789abcdef0123456789abcdef01234567"
+	parsed := &ParsedSource{Type: "git", URL: "https://example.com/owner/repo.git", Ref: commit}
+
+	_, err := FetchSource(cfg, parsed)
+	if !IsOffline(err) {
+		t.Fatalf("expected OfflineError, got %v", err)
+	}
+
+	archiveDir := t.TempDir()
+	writeTestTarGz(t, filepath.Join(archiveDir, commit+".tar.gz"), map[string]string{
+		"SKILL.md": "offline",
+	})
+	cfg.ArchiveDirs = []string{archiveDir}
+
+	src, err := FetchSource(cfg, parsed)
+	if err != nil {
+		t.Fatalf("FetchSource from archive failed: %v", err)
+	}
+	defer src.Cleanup()
+
+	if src.Commit != commit {
+		t.Errorf("expected commit %s, got %s", commit, src.Commit)
+	}
+	data, _ := os.ReadFile(filepath.Join(src.Dir, "SKILL.md"))
+	if string(data) != "offline" {
+		t.Errorf("unexpected archive content: %q", data)
+	}
+}
+
+func TestFetchSourceOfflineFromCache(t *testing.T) {
+	url, _ := initTestRepo(t)
+	cfg := &Config{RepoPath: t.TempDir()}
+	parsed := &ParsedSource{Type: "git", URL: url}
+
+	src, err := FetchSource(cfg, parsed)
+	if err != nil {
+		t.Fatalf("online FetchSource failed: %v", err)
+	}
+	src.Cleanup()
+
+	cfg.Offline = true
+	src, err = FetchSource(cfg, parsed)
+	if err != nil {
+		t.Fatalf("offline FetchSource from cache failed: %v", err)
+	}
+	src.Cleanup()
+}
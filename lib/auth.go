@@ -0,0 +1,515 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	toml "github.com/pelletier/go-toml/v2"
+)
+
+// CredentialsFileName 凭据文件名（位于仓库根目录，不要提交到版本库）
+const CredentialsFileName = "credentials.toml"
+
+// TokenEnvPrefix 按主机提供令牌的环境变量前缀，如 SKILLKIT_TOKEN_GIT_EXAMPLE_COM
+const TokenEnvPrefix = "SKILLKIT_TOKEN_"
+
+// CredentialsFile 凭据文件
+type CredentialsFile struct {
+	Credentials []Credential `toml:"credentials"`
+}
+
+// Credential 单个主机的凭据
+type Credential struct {
+	Host      string `toml:"host"`                 // 主机名，可带端口
+	Username  string `toml:"username,omitempty"`   // HTTPS 用户名，默认按平台类型选择
+	Token     string `toml:"token,omitempty"`      // 访问令牌
+	TokenEnv  string `toml:"token_env,omitempty"`  // 从该环境变量读取令牌
+	SSHKey    string `toml:"ssh_key,omitempty"`    // SSH 地址使用的私钥
+	AllowHTTP bool   `toml:"allow_http,omitempty"` // 允许通过明文 http:// 发送令牌，默认只对 https:// 地址发送
+}
+
+// GitAuth 访问某个主机使用的凭据
+type GitAuth struct {
+	Host      string
+	Username  string
+	Token     string
+	SSHKey    string
+	Origin    string // 令牌来源（环境变量名或凭据文件），用于错误提示
+	Withheld  string // 因地址为明文 http:// 而未发送的令牌来源
+	AllowHTTP bool   // 允许通过 http:// 发送令牌和凭据助手提供的凭据
+}
+
+// CredentialsPath 返回凭据文件路径，SKILLKIT_CREDENTIALS 可以覆盖
+func CredentialsPath(cfg *Config) string {
+	if path := os.Getenv("SKILLKIT_CREDENTIALS"); path != "" {
+		return ResolvePath(path)
+	}
+	if cfg == nil || cfg.RepoPath == "" {
+		return ""
+	}
+	return filepath.Join(cfg.RepoPath, CredentialsFileName)
+}
+
+// LoadCredentials 读取凭据文件，文件不存在时返回空凭据
+// 文件可被其他用户读取时拒绝使用（与 ssh 私钥相同的要求）
+func LoadCredentials(path string) (*CredentialsFile, error) {
+	if path == "" {
+		return &CredentialsFile{}, nil
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return &CredentialsFile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("%s is accessible by other users; run: chmod 600 %s", path, path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cf CredentialsFile
+	if err := toml.Unmarshal(data, &cf); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, c := range cf.Credentials {
+		registerSecret(c.Token)
+	}
+	return &cf, nil
+}
+
+// TokenEnvName 返回主机对应的令牌环境变量名
+func TokenEnvName(host string) string {
+	return TokenEnvPrefix + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, host)
+}
+
+// ResolveAuth 查找访问 rawURL 使用的凭据，本地地址返回 nil
+// 令牌依次来自 SKILLKIT_TOKEN_<HOST>、凭据文件、GH_TOKEN/GITHUB_TOKEN（仅 github.com）；
+// 都没有时不设置令牌，由 git 自己的凭据助手处理。
+// http:// 地址只在凭据文件中为主机设置了 allow_http 时使用令牌
+func ResolveAuth(cfg *Config, rawURL string) (*GitAuth, error) {
+	host, _ := remoteHost(rawURL)
+	if host == "" {
+		return nil, nil
+	}
+	if u, err := url.Parse(rawURL); err == nil && u.User != nil {
+		pass, _ := u.User.Password()
+		registerSecret(pass)
+	}
+
+	auth := &GitAuth{Host: host}
+	creds, err := LoadCredentials(CredentialsPath(cfg))
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range creds.Credentials {
+		if strings.EqualFold(c.Host, host) {
+			auth.Username = c.Username
+			auth.AllowHTTP = c.AllowHTTP
+			if c.SSHKey != "" {
+				auth.SSHKey = ResolvePath(c.SSHKey)
+			}
+			if c.TokenEnv != "" {
+				auth.Token, auth.Origin = os.Getenv(c.TokenEnv), c.TokenEnv
+			} else if c.Token != "" {
+				auth.Token, auth.Origin = c.Token, CredentialsFileName
+			}
+			break
+		}
+	}
+
+	if token := os.Getenv(TokenEnvName(host)); token != "" {
+		auth.Token, auth.Origin = token, TokenEnvName(host)
+	} else if auth.Token == "" && host == "github.com" {
+		for _, name := range []string{"GH_TOKEN", "GITHUB_TOKEN"} {
+			if token := os.Getenv(name); token != "" {
+				auth.Token, auth.Origin = token, name
+				break
+			}
+		}
+	}
+	if auth.Token == "" {
+		auth.Origin = ""
+	}
+	registerSecret(auth.Token)
+	if auth.Token != "" && isPlainHTTP(rawURL) && !auth.AllowHTTP {
+		auth.Token, auth.Origin, auth.Withheld = "", "", auth.Origin
+	}
+
+	if auth.Username == "" {
+		auth.Username = defaultUsername(cfg, host)
+	}
+	return auth, nil
+}
+
+// defaultUsername 令牌认证使用的 HTTPS 用户名
+func defaultUsername(cfg *Config, host string) string {
+	var hosts *HostRegistry
+	if cfg != nil {
+		hosts = cfg.HostRegistry()
+	}
+	typ, _ := hosts.Lookup(host)
+	switch typ {
+	case HostGitHub:
+		return "x-access-token"
+	case HostBitbucket:
+		return "x-token-auth"
+	}
+	return "oauth2"
+}
+
+// isPlainHTTP 检查地址是否为明文 http://
+func isPlainHTTP(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && u.Scheme == "http"
+}
+
+// remoteHost 返回远程地址的主机名（HTTP(S) 地址保留端口）和是否为 SSH 地址，本地地址返回空
+func remoteHost(rawURL string) (string, bool) {
+	if u, err := url.Parse(rawURL); err == nil && u.Scheme != "" && len(u.Scheme) > 1 {
+		switch u.Scheme {
+		case "http", "https":
+			return strings.ToLower(u.Host), false
+		case "ssh", "git+ssh", "ssh+git":
+			return strings.ToLower(u.Hostname()), true
+		case "git":
+			return strings.ToLower(u.Hostname()), false
+		}
+		return "", false
+	}
+	// scp 形式: git@host:owner/repo.git
+	if at := strings.Index(rawURL, "@"); at >= 0 && !strings.Contains(rawURL[:at], "/") {
+		rest := rawURL[at+1:]
+		if colon := strings.Index(rest, ":"); colon > 0 && !strings.Contains(rest[:colon], "/") {
+			return strings.ToLower(rest[:colon]), true
+		}
+	}
+	return "", false
+}
+
+// gitEnv 返回访问 remote 的 git 命令环境变量
+// 令牌通过 http.<url>.extraHeader 传给 git（不出现在命令行和仓库配置中），http:// 地址须设置 allow_http；
+// 非交互运行时禁止 git 提示输入
+func gitEnv(auth *GitAuth, remote string) []string {
+	env := os.Environ()
+	interactive := IsInteractive()
+	if !interactive {
+		env = append(env, "GIT_TERMINAL_PROMPT=0")
+	}
+	if remote == "" {
+		return env
+	}
+
+	_, ssh := remoteHost(remote)
+	if ssh {
+		var opts []string
+		if auth != nil && auth.SSHKey != "" {
+			opts = append(opts, "-i", shellQuote(auth.SSHKey), "-o", "IdentitiesOnly=yes")
+		}
+		if !interactive {
+			opts = append(opts, "-o", "BatchMode=yes")
+		}
+		if len(opts) > 0 && (os.Getenv("GIT_SSH_COMMAND") == "" || auth != nil && auth.SSHKey != "") {
+			env = append(env, "GIT_SSH_COMMAND=ssh "+strings.Join(opts, " "))
+		}
+		return env
+	}
+
+	u, err := url.Parse(remote)
+	if auth == nil || auth.Token == "" || err != nil || (u.Scheme != "https" && !(u.Scheme == "http" && auth.AllowHTTP)) {
+		return env
+	}
+	n, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	header := "Authorization: Basic " + basicAuth(auth.Username, auth.Token)
+	return append(env,
+		fmt.Sprintf("GIT_CONFIG_COUNT=%d", n+1),
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=http.%s://%s/.extraHeader", n, u.Scheme, u.Host),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", n, header),
+	)
+}
+
+func basicAuth(user, pass string) string {
+	req := &http.Request{Header: http.Header{}}
+	req.SetBasicAuth(user, pass)
+	return strings.TrimPrefix(req.Header.Get("Authorization"), "Basic ")
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// runGit 执行 git 命令：错误输出脱敏后转发到 stderr，认证失败时返回 AuthError
+// remote 为命令访问的远程地址，本地操作传空
+func runGit(auth *GitAuth, remote string, args ...string) error {
+	var captured bytes.Buffer
+	stderr := &redactWriter{w: os.Stderr, captured: &captured}
+
+	cmd := exec.Command("git", args...)
+	cmd.Env = gitEnv(auth, remote)
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	stderr.Flush()
+	if err != nil && remote != "" {
+		if reason := authFailure(captured.String()); reason != "" {
+			return newAuthError(auth, remote, reason)
+		}
+	}
+	return err
+}
+
+// gitError 包装 git 命令错误，认证失败原样返回以便调用方识别
+func gitError(msg string, err error) error {
+	if IsAuthError(err) {
+		return err
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// authFailurePatterns git 输出中表示认证失败的信息（小写）-> 原因
+var authFailurePatterns = []struct{ pattern, reason string }{
+	{"terminal prompts disabled", "credentials required"},
+	{"could not read username", "credentials required"},
+	{"could not read password", "credentials required"},
+	{"authentication failed", "credentials rejected"},
+	{"invalid username or password", "credentials rejected"},
+	{"returned error: 401", "HTTP 401 Unauthorized"},
+	{"returned error: 403", "HTTP 403 Forbidden"},
+	{"permission denied (publickey", "SSH key rejected"},
+	{"repository not found", "repository not found or access denied"},
+}
+
+// authFailure 从 git 错误输出中识别认证失败，返回原因
+func authFailure(stderr string) string {
+	lower := strings.ToLower(stderr)
+	for _, p := range authFailurePatterns {
+		if strings.Contains(lower, p.pattern) {
+			return p.reason
+		}
+	}
+	return ""
+}
+
+func newAuthError(auth *GitAuth, remote, reason string) *AuthError {
+	host, ssh := remoteHost(remote)
+	e := &AuthError{URL: Redact(remote), Host: host, SSH: ssh, Reason: reason}
+	if auth != nil && !ssh {
+		e.Withheld = auth.Withheld
+	}
+	if auth != nil && !ssh && auth.Origin != "" {
+		e.Origin = auth.Origin
+		if reason == "credentials required" {
+			e.Reason = "credentials rejected"
+		}
+	}
+	return e
+}
+
+// httpGet 发送 GET 请求，按主机附加令牌
+// 未配置令牌且服务器返回 401 时向 git 凭据助手获取凭据重试（http:// 地址须设置 allow_http）；认证失败返回 AuthError
+func httpGet(cfg *Config, rawURL string, timeout time.Duration, accept string) (*http.Response, error) {
+	auth, err := ResolveAuth(cfg, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: timeout}
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", "skillkit/"+Version)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		return req, nil
+	}
+
+	req, err := newRequest()
+	if err != nil {
+		return nil, err
+	}
+	if auth != nil && auth.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %s", Redact(rawURL), Redact(err.Error()))
+	}
+
+	helperAllowed := !isPlainHTTP(rawURL) || auth != nil && auth.AllowHTTP
+	if resp.StatusCode == http.StatusUnauthorized && helperAllowed && (auth == nil || auth.Token == "") {
+		if user, pass, ok := credentialFill(rawURL); ok {
+			resp.Body.Close()
+			if req, err = newRequest(); err != nil {
+				return nil, err
+			}
+			req.SetBasicAuth(user, pass)
+			if resp, err = client.Do(req); err != nil {
+				return nil, fmt.Errorf("GET %s: %s", Redact(rawURL), Redact(err.Error()))
+			}
+			action := "approve"
+			if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+				action = "reject"
+			}
+			credentialStore(action, rawURL, user, pass)
+			if action == "reject" {
+				resp.Body.Close()
+				e := newAuthError(auth, rawURL, "HTTP "+resp.Status)
+				e.Origin = "git credential helper"
+				return nil, e
+			}
+		}
+	}
+
+	sentCredentials := auth != nil && auth.Token != ""
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden && sentCredentials {
+		resp.Body.Close()
+		return nil, newAuthError(auth, rawURL, "HTTP "+resp.Status)
+	}
+	return resp, nil
+}
+
+// credentialFill 通过 git credential fill 向已配置的凭据助手获取 HTTP(S) 地址的凭据
+func credentialFill(rawURL string) (user, pass string, ok bool) {
+	input, ok := credentialInput(rawURL)
+	if !ok {
+		return "", "", false
+	}
+	cmd := exec.Command("git", "credential", "fill")
+	cmd.Env = gitEnv(nil, "")
+	cmd.Stdin = strings.NewReader(input)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", "", false
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if k, v, found := strings.Cut(line, "="); found {
+			switch k {
+			case "username":
+				user = v
+			case "password":
+				pass = v
+			}
+		}
+	}
+	registerSecret(pass)
+	return user, pass, pass != ""
+}
+
+// credentialStore 把凭据的验证结果（approve 或 reject）告知凭据助手
+func credentialStore(action, rawURL, user, pass string) {
+	input, ok := credentialInput(rawURL)
+	if !ok {
+		return
+	}
+	input = strings.TrimSuffix(input, "\n") + "username=" + user + "\npassword=" + pass + "\n\n"
+	cmd := exec.Command("git", "credential", action)
+	cmd.Env = gitEnv(nil, "")
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Run()
+}
+
+func credentialInput(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", false
+	}
+	return fmt.Sprintf("protocol=%s\nhost=%s\n\n", u.Scheme, u.Host), true
+}
+
+// 已知的令牌，输出前被替换
+var (
+	secretsMu sync.Mutex
+	secrets   = make(map[string]bool)
+)
+
+// registerSecret 记录需要从输出中隐去的令牌
+func registerSecret(s string) {
+	if len(s) < 4 {
+		return
+	}
+	secretsMu.Lock()
+	secrets[s] = true
+	secretsMu.Unlock()
+}
+
+// urlUserinfo 匹配 URL 中的用户信息
+var urlUserinfo = regexp.MustCompile(`\b([a-zA-Z][a-zA-Z0-9+.-]*)://([^/@\s'"]+)@`)
+
+// Redact 隐去文本中已知的令牌和 URL 中的密码；HTTP(S) 地址只有用户名时也隐去（可能是令牌）
+func Redact(s string) string {
+	secretsMu.Lock()
+	for secret := range secrets {
+		s = strings.ReplaceAll(s, secret, "***")
+	}
+	secretsMu.Unlock()
+
+	return urlUserinfo.ReplaceAllStringFunc(s, func(m string) string {
+		parts := urlUserinfo.FindStringSubmatch(m)
+		scheme, userinfo := parts[1], parts[2]
+		if user, _, ok := strings.Cut(userinfo, ":"); ok {
+			return scheme + "://" + user + ":***@"
+		}
+		if scheme == "http" || scheme == "https" {
+			return scheme + "://***@"
+		}
+		return m
+	})
+}
+
+// StripCredentials 去掉 HTTP(S) 地址中的用户信息，用于写入锁文件
+func StripCredentials(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.User == nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return rawURL
+	}
+	u.User = nil
+	return u.String()
+}
+
+// redactWriter 按行脱敏后转发输出，同时保留一份用于识别错误
+type redactWriter struct {
+	w        io.Writer
+	captured *bytes.Buffer
+	pending  []byte
+}
+
+func (r *redactWriter) Write(p []byte) (int, error) {
+	r.captured.Write(p)
+	r.pending = append(r.pending, p...)
+	if i := bytes.LastIndexAny(r.pending, "\r\n"); i >= 0 {
+		r.w.Write([]byte(Redact(string(r.pending[:i+1]))))
+		r.pending = r.pending[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush 输出剩余的不完整行
+func (r *redactWriter) Flush() {
+	if len(r.pending) > 0 {
+		r.w.Write([]byte(Redact(string(r.pending))))
+		r.pending = nil
+	}
+}
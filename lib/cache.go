@@ -0,0 +1,309 @@
+package lib
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCacheMaxSize 默认缓存大小上限
+const DefaultCacheMaxSize = "1GB"
+
+// CacheConfig 仓库缓存配置 (platforms.toml 中的 [cache])
+type CacheConfig struct {
+	MaxSize  string `toml:"max_size,omitempty"` // 如 "500MB", "2GB"
+	Disabled bool   `toml:"disabled,omitempty"`
+}
+
+// CacheEntry 缓存中的单个裸仓库
+type CacheEntry struct {
+	Key      string // host/owner/repo
+	Path     string
+	Size     int64
+	LastUsed time.Time
+}
+
+// CacheRoot 返回 git 缓存根目录
+func CacheRoot(cfg *Config) string {
+	return filepath.Join(cfg.RepoPath, ".cache", "git")
+}
+
+// CacheMaxSize 返回缓存大小上限（字节）
+func (cfg *Config) CacheMaxSize() (int64, error) {
+	size := cfg.Cache.MaxSize
+	if size == "" {
+		size = DefaultCacheMaxSize
+	}
+	return ParseSize(size)
+}
+
+// CacheKey 根据仓库 URL 生成缓存相对路径: <host>/<owner>/<repo>
+func CacheKey(rawURL string) string {
+	host := ""
+	path := rawURL
+
+	if u, err := url.Parse(rawURL); err == nil && u.Scheme != "" && u.Scheme != "file" {
+		host = u.Host // 保留端口，不同端口上的同名仓库不共用缓存
+		path = u.Path
+	} else if u != nil && u.Scheme == "file" {
+		host = "local"
+		path = u.Path
+	} else if at := strings.Index(rawURL, "@"); at >= 0 && strings.Contains(rawURL[at:], ":") {
+		// scp 形式: git@host:owner/repo.git
+		rest := rawURL[at+1:]
+		colon := strings.Index(rest, ":")
+		host = rest[:colon]
+		path = rest[colon+1:]
+	} else {
+		host = "local"
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	var parts []string
+	for _, p := range append([]string{host}, strings.Split(path, "/")...) {
+		p = sanitizeCacheSegment(p)
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return filepath.Join(parts...)
+}
+
+func sanitizeCacheSegment(s string) string {
+	if s == "." || s == ".." {
+		return ""
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' || r < 0x20 {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// cloneViaCache 通过本地裸仓库缓存克隆：先增量获取到缓存，再从缓存本地克隆到临时目录
+func cloneViaCache(cfg *Config, auth *GitAuth, repoURL, ref string) (string, error) {
+	cacheDir := filepath.Join(CacheRoot(cfg), CacheKey(repoURL))
+	if err := updateCache(auth, cacheDir, repoURL, ref); err != nil {
+		return "", err
+	}
+
+	tempDir, err := cloneFromCache(cacheDir, ref)
+	if err != nil {
+		return "", err
+	}
+
+	// 按大小上限清理（临时检出不依赖缓存，可安全删除）
+	if maxSize, err := cfg.CacheMaxSize(); err == nil {
+		PruneCache(cfg, maxSize)
+	}
+	return tempDir, nil
+}
+
+// cloneFromCache 从缓存中的裸仓库检出到临时目录（不访问网络）
+func cloneFromCache(cacheDir, ref string) (string, error) {
+	if _, err := os.Stat(filepath.Join(cacheDir, "HEAD")); err != nil {
+		return "", fmt.Errorf("not cached: %s", cacheDir)
+	}
+
+	tempDir, err := os.MkdirTemp("", "skillkit-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+
+	var steps [][]string
+	if IsCommitSHA(ref) && !hasBranchOrTag(cacheDir, ref) {
+		steps = [][]string{
+			{"clone", "--quiet", "--no-checkout", cacheDir, tempDir},
+			{"-C", tempDir, "checkout", "--quiet", "--detach", ref},
+		}
+	} else {
+		args := []string{"clone", "--quiet"}
+		if ref != "" {
+			args = append(args, "--branch", ref)
+		}
+		steps = [][]string{append(args, cacheDir, tempDir)}
+	}
+	if err := runGitSteps(nil, "", steps); err != nil {
+		os.RemoveAll(tempDir)
+		return "", fmt.Errorf("checkout from cache failed: %w", err)
+	}
+
+	// 更新最近使用时间，用于按 LRU 清理
+	now := time.Now()
+	os.Chtimes(cacheDir, now, now)
+
+	return tempDir, nil
+}
+
+// updateCache 创建或增量更新缓存中的裸仓库
+func updateCache(auth *GitAuth, cacheDir, repoURL, ref string) error {
+	if _, err := os.Stat(filepath.Join(cacheDir, "HEAD")); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(cacheDir), 0755); err != nil {
+			return fmt.Errorf("failed to create cache directory: %w", err)
+		}
+		steps := [][]string{
+			{"clone", "--quiet", "--bare", repoURL, cacheDir},
+			{"-C", cacheDir, "config", "remote.origin.fetch", "+refs/heads/*:refs/heads/*"},
+		}
+		if err := runGitSteps(auth, repoURL, steps); err != nil {
+			os.RemoveAll(cacheDir)
+			return gitError("git clone failed", err)
+		}
+	} else {
+		if err := runGit(auth, repoURL, "-C", cacheDir, "fetch", "--quiet", "--prune", "--tags", "origin"); err != nil {
+			return gitError("git fetch failed", err)
+		}
+	}
+
+	// 未公开的提交（如已删除分支上的提交）需要单独获取
+	if IsCommitSHA(ref) && !hasCommit(cacheDir, ref) {
+		if err := runGit(auth, repoURL, "-C", cacheDir, "fetch", "--quiet", "origin", ref); err != nil {
+			return gitError("git fetch "+ref+" failed", err)
+		}
+	}
+	return nil
+}
+
+func hasCommit(dir, commit string) bool {
+	return exec.Command("git", "-C", dir, "cat-file", "-e", commit+"^{commit}").Run() == nil
+}
+
+// hasBranchOrTag 检查仓库中是否有名为 ref 的分支或标签
+func hasBranchOrTag(dir, ref string) bool {
+	for _, prefix := range []string{"refs/heads/", "refs/tags/"} {
+		if exec.Command("git", "-C", dir, "show-ref", "--verify", "--quiet", prefix+ref).Run() == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// ListCache 列出缓存中的所有仓库，按最近使用时间倒序
+func ListCache(cfg *Config) ([]CacheEntry, error) {
+	root := CacheRoot(cfg)
+	var entries []CacheEntry
+
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return filepath.SkipAll
+			}
+			return err
+		}
+		if !d.IsDir() || path == root {
+			return nil
+		}
+		if _, err := os.Stat(filepath.Join(path, "HEAD")); err != nil {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size, err := dirSize(path)
+		if err != nil {
+			return err
+		}
+		key, _ := filepath.Rel(root, path)
+		entries = append(entries, CacheEntry{
+			Key:      filepath.ToSlash(key),
+			Path:     path,
+			Size:     size,
+			LastUsed: info.ModTime(),
+		})
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].LastUsed.After(entries[j].LastUsed) })
+	return entries, nil
+}
+
+// PruneCache 按最近最少使用顺序删除缓存，直到总大小不超过 maxSize
+// maxSize 为 0 时清空缓存。返回被删除的条目。
+func PruneCache(cfg *Config, maxSize int64) ([]CacheEntry, error) {
+	entries, err := ListCache(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+	}
+
+	var removed []CacheEntry
+	for i := len(entries) - 1; i >= 0 && total > maxSize; i-- {
+		if err := os.RemoveAll(entries[i].Path); err != nil {
+			return removed, err
+		}
+		total -= entries[i].Size
+		removed = append(removed, entries[i])
+	}
+	return removed, nil
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// ParseSize 解析大小字符串，如 "500MB", "2GB", "1024"
+func ParseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	units := []struct {
+		suffix string
+		mult   int64
+	}{
+		{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1},
+	}
+	mult := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, u.suffix))
+			mult = u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size: %s", s)
+	}
+	return int64(n * float64(mult)), nil
+}
+
+// FormatSize 格式化字节数
+func FormatSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1fGB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
+}
@@ -0,0 +1,110 @@
+package lib
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCacheKey(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://github.com/owner/repo.git", "github.com/owner/repo"},
+		{"https://gitlab.com/group/sub/repo.git", "gitlab.com/group/sub/repo"},
+		{"git@github.com:owner/repo.git", "github.com/owner/repo"},
+		{"ssh://git@git.example.com:2222/owner/repo", "git.example.com_2222/owner/repo"},
+		{"https://git.example.com:8443/owner/repo.git", "git.example.com_8443/owner/repo"},
+		{"file:///srv/git/repo.git", "local/srv/git/repo"},
+		{"https://github.com/owner/../../etc", "github.com/owner/etc"},
+	}
+
+	for _, tt := range tests {
+		if got := filepath.ToSlash(CacheKey(tt.url)); got != tt.expected {
+			t.Errorf("CacheKey(%s) = %s, expected %s", tt.url, got, tt.expected)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"1024", 1024},
+		{"500MB", 500 << 20},
+		{"2gb", 2 << 30},
+		{"1.5KB", 1536},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.input)
+		if err != nil || got != tt.expected {
+			t.Errorf("ParseSize(%s) = %d, %v; expected %d", tt.input, got, err, tt.expected)
+		}
+	}
+	if _, err := ParseSize("lots"); err == nil {
+		t.Error("expected error for invalid size")
+	}
+}
+
+func TestFetchSourceUsesCache(t *testing.T) {
+	url, first := initTestRepo(t)
+	cfg := &Config{RepoPath: t.TempDir()}
+	parsed := &ParsedSource{Type: "git", URL: url}
+
+	src, err := FetchSource(cfg, parsed)
+	if err != nil {
+		t.Fatalf("FetchSource failed: %v", err)
+	}
+	src.Cleanup()
+
+	entries, err := ListCache(cfg)
+	if err != nil {
+		t.Fatalf("ListCache failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 cached repository, got %d", len(entries))
+	}
+
+	// 上游新增提交后，缓存应增量获取到最新内容
+	repoDir := strings.TrimPrefix(url, "file://")
+	os.WriteFile(filepath.Join(repoDir, "SKILL.md"), []byte("v3"), 0644)
+	out, err := exec.Command("git", "-C", repoDir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-am", "v3").CombinedOutput()
+	if err != nil {
+		t.Fatalf("git commit failed: %v\n%s", err, out)
+	}
+
+	src, err = FetchSource(cfg, parsed)
+	if err != nil {
+		t.Fatalf("FetchSource after upstream change failed: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(src.Dir, "SKILL.md"))
+	src.Cleanup()
+	if string(data) != "v3" {
+		t.Errorf("expected cache to pick up new commit, got %q", data)
+	}
+
+	// 固定提交从缓存检出
+	src, err = FetchSource(cfg, &ParsedSource{Type: "git", URL: url, Ref: first})
+	if err != nil {
+		t.Fatalf("FetchSource at commit failed: %v", err)
+	}
+	defer src.Cleanup()
+	if src.Commit != first {
+		t.Errorf("expected commit %s, got %s", first, src.Commit)
+	}
+
+	removed, err := PruneCache(cfg, 0)
+	if err != nil {
+		t.Fatalf("PruneCache failed: %v", err)
+	}
+	if len(removed) != 1 {
+		t.Errorf("expected 1 removed entry, got %d", len(removed))
+	}
+	if _, err := os.Stat(filepath.Join(src.Dir, "SKILL.md")); err != nil {
+		t.Error("checkout should not depend on the pruned cache")
+	}
+}
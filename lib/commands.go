@@ -9,13 +9,23 @@ type Command struct {
 
 // Commands 命令注册表
 var Commands = []Command{
-	{"add", "Download skill from git repo or local path", "sk add <source>"},
-	{"use", "Distribute a skill/agent to platform(s)", "sk use <module> [platform]"},
-	{"list", "List all modules and their link status", "sk list"},
+	{"add", "Download skill from git repo, archive or local path", "sk add <source> [--ref <ref>] [--sha256 <hex>] [--offline]"},
+	{"update", "Re-fetch installed modules from their recorded origin", "sk update [module...] [--dry-run] [--offline]"},
+	{"restore", "Rebuild the skill pool from a lockfile", "sk restore [lockfile] [--no-link] [--offline]"},
+	{"install", "Install and link the modules listed in the project's .skillkit.toml", "sk install [--relative] [--dry-run] [--atomic] [--offline]"},
+	{"vendor", "Copy modules into the project for committing", "sk vendor <module...> [--platform <platform>]... | sk vendor --update [module...]"},
+	{"search", "Search the configured skill indexes and install results", "sk search <query> [--index <name>]... [--refresh] [--offline]"},
+	{"registry", "Build or serve a skill index from this repository", "sk registry build [-o <file>] [--source-base <source>] | sk registry serve [--addr <host:port>] [--index <file>]"},
+	{"cache", "List or prune the git repository cache", "sk cache [list|prune]"},
+	{"use", "Distribute a skill/agent to platform(s)", "sk use <module> [platform] [--global|--project|--all-scopes] [--relative] [--dry-run] [--atomic]"},
+	{"list", "List all modules and their link status", "sk list [--global|--project|--all-scopes]"},
 	{"platforms", "Show registered platforms", "sk platforms"},
-	{"info", "Show module details and aliases", "sk info <module>"},
-	{"remove", "Remove symlinks for a module", "sk remove <module> [platform]"},
-	{"status", "Health check: detect broken symlinks", "sk status"},
+	{"info", "Show module details and aliases", "sk info <module> [--global|--project|--all-scopes]"},
+	{"remove", "Remove symlinks for a module", "sk remove <module> [platform] [--global|--project|--all-scopes]"},
+	{"status", "Health check: detect broken symlinks", "sk status [--check] [--global|--project|--all-scopes]"},
+	{"doctor", "Find and repair broken, stale and orphaned links", "sk doctor [--fix] [--yes] [--global|--project]"},
+	{"new", "Create a skill or agent from a template", "sk new <skill|agent> <name> [--description <text>] [--template <name>] [--as <name>] [--alias <platform>=<name>]... [--scripts] [--references] [--use]"},
+	{"lint", "Check skills against the Agent Skills specification", "sk lint [module|path...]"},
 	{"init", "Initialize the agent repository", "sk init"},
 	{"help", "Show help message", "sk -h"},
 	{"version", "Show version", "sk -v"},
@@ -1,8 +1,10 @@
 package lib
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	toml "github.com/pelletier/go-toml/v2"
 )
@@ -14,6 +16,15 @@ type Config struct {
 	Platforms        map[string]Platform `toml:"platforms"`
 	DefaultPlatforms []string            `toml:"default_platforms"` // 默认同步的平台列表
 	PlatformOrder    []string            `toml:"platform_order"`    // 平台显示顺序
+	Cache            CacheConfig         `toml:"cache,omitempty"`   // 仓库缓存配置
+	Indexes          []IndexConfig       `toml:"indexes,omitempty"` // sk search 使用的技能索引
+	Hosts            []HostConfig        `toml:"hosts,omitempty"`   // 自建代码托管平台
+
+	Offline     bool     `toml:"-"` // 离线模式：只使用缓存和归档
+	ArchiveDirs []string `toml:"-"` // 离线归档目录
+	ProjectRoot string   `toml:"-"` // 项目根目录，不在项目中时为空
+
+	hosts *HostRegistry
 }
 
 // Platform 平台配置
@@ -23,6 +34,10 @@ type Platform struct {
 	Global   string `toml:"global"`
 	SkillDir string `toml:"skill_dir"`
 	AgentDir string `toml:"agent_dir"`
+	LinkMode string `toml:"link_mode,omitempty"` // symlink (默认) | copy | hardlink
+
+	projectRoot  string                 // 项目目录的解析基准，为空时相对当前目录
+	projectLinks map[string]*LinkConfig // 项目清单中的链接名（按模块目录名）
 }
 
 // GetCategoryDir 根据类别返回目录名
@@ -35,6 +50,8 @@ func (p Platform) GetCategoryDir(category string) string {
 
 // LoadConfig 加载配置
 // 配置路径优先级: SKILLKIT_CONFIG 环境变量 > ~/.config/agent/platforms.toml > 可执行文件目录
+// SKILLKIT_OFFLINE=1 启用离线模式
+// 项目根目录从当前目录向上查找（见 FindProjectRoot）
 func LoadConfig() (*Config, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -71,11 +88,36 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	if cfg.hosts, err = NewHostRegistry(cfg.Hosts); err != nil {
+		return nil, fmt.Errorf("%s: %w", configPath, err)
+	}
+
 	cfg.RepoPath = repoPath
 	cfg.ConfigPath = configPath
+	cfg.Offline = isTruthy(os.Getenv("SKILLKIT_OFFLINE"))
+	cfg.ArchiveDirs = []string{filepath.Join(repoPath, ArchiveDirName)}
+	if cwd, err := os.Getwd(); err == nil {
+		cfg.SetProjectRoot(FindProjectRoot(cwd, cfg.Platforms))
+	}
 	return &cfg, nil
 }
 
+// HostRegistry 返回内置平台和 [[hosts]] 组成的托管平台注册表
+func (cfg *Config) HostRegistry() *HostRegistry {
+	if cfg.hosts == nil && len(cfg.Hosts) > 0 {
+		cfg.hosts, _ = NewHostRegistry(cfg.Hosts)
+	}
+	return cfg.hosts
+}
+
+func isTruthy(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
 // SaveConfig 保存配置
 func SaveConfig(cfg *Config) error {
 	configPath := cfg.ConfigPath
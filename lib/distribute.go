@@ -0,0 +1,294 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	toml "github.com/pelletier/go-toml/v2"
+)
+
+// 分发方式 (platforms.toml 中的 link_mode)
+const (
+	LinkModeSymlink  = "symlink"
+	LinkModeRelative = "relative" // 相对路径软链接
+	LinkModeCopy     = "copy"
+	LinkModeHardlink = "hardlink"
+)
+
+// CopyMarkerName 复制分发时写入目标目录的标记文件
+const CopyMarkerName = ".skillkit-copy.toml"
+
+// 复制分发的状态
+const (
+	CopyStateOK       = "ok"       // 与源一致
+	CopyStateOutdated = "outdated" // 源已变更
+	CopyStateModified = "modified" // 副本被本地修改
+	CopyStateForeign  = "foreign"  // 副本来自其他源
+)
+
+// CopyMarker 复制分发记录
+type CopyMarker struct {
+	Source    string    `toml:"source"`
+	Mode      string    `toml:"mode"`
+	Hash      string    `toml:"hash"` // 写入时的内容哈希（不含标记文件）
+	CreatedAt time.Time `toml:"created_at"`
+}
+
+// GetLinkMode 返回平台的分发方式，默认为软链接
+func (p Platform) GetLinkMode() string {
+	switch p.LinkMode {
+	case LinkModeRelative, LinkModeCopy, LinkModeHardlink:
+		return p.LinkMode
+	}
+	return LinkModeSymlink
+}
+
+// Distribute 按分发方式将模块分发到目标路径
+func Distribute(source, target, mode string) error {
+	switch mode {
+	case LinkModeCopy:
+		return createCopy(source, target, false)
+	case LinkModeHardlink:
+		return createCopy(source, target, true)
+	case LinkModeRelative:
+		return CreateRelativeSymlink(source, target)
+	}
+	return CreateSymlink(source, target, false)
+}
+
+// Undistribute 移除分发结果：软链接直接删除；副本（含 vendor 副本）仅在由 Skill Kit 创建且未被修改时删除
+func Undistribute(target string) error {
+	target = ResolvePath(target)
+
+	info, err := os.Lstat(target)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		return os.Remove(target)
+	}
+
+	copyMarker, copyErr := ReadCopyMarker(target)
+	vendorMarker, vendorErr := ReadVendorMarker(target)
+	if copyErr != nil && vendorErr != nil {
+		return fmt.Errorf("target is not a symlink or a Skill Kit copy: %s", target)
+	}
+	hash, err := HashDir(target)
+	if err != nil {
+		return err
+	}
+	modified := false
+	if copyErr == nil {
+		modified, err = copyModified(target, copyMarker, hash)
+		if err != nil {
+			return err
+		}
+	} else {
+		modified = hash != vendorMarker.Hash
+	}
+	if modified {
+		return fmt.Errorf("copy has local changes, refusing to remove: %s", target)
+	}
+	return os.RemoveAll(target)
+}
+
+// IsDistributed 检查目标是否为软链接、Skill Kit 创建的副本或 vendor 副本
+func IsDistributed(target string) bool {
+	return IsSymlink(target) || IsManagedCopy(target) || IsVendored(target)
+}
+
+// IsManagedCopy 检查目标是否为 Skill Kit 创建的副本
+func IsManagedCopy(target string) bool {
+	if IsSymlink(target) {
+		return false
+	}
+	_, err := ReadCopyMarker(target)
+	return err == nil
+}
+
+// ReadCopyMarker 读取副本的标记文件
+func ReadCopyMarker(target string) (*CopyMarker, error) {
+	data, err := os.ReadFile(filepath.Join(target, CopyMarkerName))
+	if err != nil {
+		return nil, err
+	}
+	var marker CopyMarker
+	if err := toml.Unmarshal(data, &marker); err != nil {
+		return nil, err
+	}
+	return &marker, nil
+}
+
+// CopyState 比较副本与源模块，返回 CopyState* 之一
+func CopyState(source, target string) (string, error) {
+	marker, err := ReadCopyMarker(target)
+	if err != nil {
+		return "", err
+	}
+	if ResolvePath(marker.Source) != ResolvePath(source) {
+		return CopyStateForeign, nil
+	}
+
+	targetHash, err := HashDir(target)
+	if err != nil {
+		return "", err
+	}
+	modified, err := copyModified(target, marker, targetHash)
+	if err != nil {
+		return "", err
+	}
+	if modified {
+		return CopyStateModified, nil
+	}
+
+	sourceHash, err := HashDir(source)
+	if err != nil {
+		return "", err
+	}
+	if sourceHash != targetHash {
+		return CopyStateOutdated, nil
+	}
+	return CopyStateOK, nil
+}
+
+// copyModified 检查副本是否有本地修改，hash 为副本当前的内容哈希
+// 硬链接副本与源共享文件，源被原地编辑时副本随之变化，只要文件仍与源相同就不算本地修改
+func copyModified(target string, marker *CopyMarker, hash string) (bool, error) {
+	if hash == marker.Hash {
+		return false, nil
+	}
+	if marker.Mode != LinkModeHardlink {
+		return true, nil
+	}
+	linked, err := linkedToSource(ResolvePath(marker.Source), target)
+	return !linked, err
+}
+
+// linkedToSource 检查硬链接副本中的每个文件是否仍与源中对应文件为同一文件
+func linkedToSource(source, target string) (bool, error) {
+	linked := true
+	err := filepath.WalkDir(target, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path == filepath.Join(target, CopyMarkerName) {
+			return nil
+		}
+		rel, err := filepath.Rel(target, path)
+		if err != nil {
+			return err
+		}
+		targetInfo, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		sourceInfo, err := os.Lstat(filepath.Join(source, rel))
+		if err != nil || !os.SameFile(sourceInfo, targetInfo) {
+			linked = false
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return linked, err
+}
+
+// createCopy 复制（或硬链接）模块到目标路径并写入标记文件
+func createCopy(source, target string, hardlink bool) error {
+	source = ResolvePath(source)
+	target = ResolvePath(target)
+
+	if _, err := os.Stat(source); os.IsNotExist(err) {
+		return fmt.Errorf("source not found: %s", source)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	// 目标已存在：软链接和未修改的副本可以替换，其余报错保护
+	if info, err := os.Lstat(target); err == nil {
+		if info.Mode()&os.ModeSymlink != 0 {
+			if err := os.Remove(target); err != nil {
+				return fmt.Errorf("failed to remove existing symlink: %v", err)
+			}
+		} else if IsManagedCopy(target) {
+			if err := Undistribute(target); err != nil {
+				return err
+			}
+		} else if info.IsDir() {
+			return fmt.Errorf("target is a real directory (not symlink): %s", target)
+		} else {
+			return fmt.Errorf("target is a real file (not symlink): %s", target)
+		}
+	}
+
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	mode := LinkModeCopy
+	var err error
+	if hardlink {
+		mode = LinkModeHardlink
+		err = linkDir(source, target)
+	} else {
+		err = copyDir(source, target)
+	}
+	if err != nil {
+		os.RemoveAll(target)
+		return fmt.Errorf("failed to %s %s: %w", mode, source, err)
+	}
+
+	hash, err := HashDir(target)
+	if err != nil {
+		os.RemoveAll(target)
+		return err
+	}
+	marker := CopyMarker{
+		Source:    source,
+		Mode:      mode,
+		Hash:      hash,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	data, err := toml.Marshal(marker)
+	if err != nil {
+		os.RemoveAll(target)
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(target, CopyMarkerName), data, 0644); err != nil {
+		os.RemoveAll(target)
+		return err
+	}
+	return nil
+}
+
+// linkDir 以硬链接方式镜像目录树（目录本身无法硬链接，需逐个创建）
+func linkDir(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		srcPath := filepath.Join(src, entry.Name())
+		dstPath := filepath.Join(dst, entry.Name())
+
+		if entry.IsDir() {
+			if err := os.MkdirAll(dstPath, 0755); err != nil {
+				return err
+			}
+			if err := linkDir(srcPath, dstPath); err != nil {
+				return err
+			}
+		} else if err := os.Link(srcPath, dstPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
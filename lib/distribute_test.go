@@ -0,0 +1,143 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetLinkMode(t *testing.T) {
+	tests := []struct {
+		mode     string
+		expected string
+	}{
+		{"", LinkModeSymlink},
+		{"relative", LinkModeRelative},
+		{"copy", LinkModeCopy},
+		{"hardlink", LinkModeHardlink},
+		{"bogus", LinkModeSymlink},
+	}
+	for _, tt := range tests {
+		if got := (Platform{LinkMode: tt.mode}).GetLinkMode(); got != tt.expected {
+			t.Errorf("GetLinkMode(%q) = %s, expected %s", tt.mode, got, tt.expected)
+		}
+	}
+}
+
+func TestDistributeCopy(t *testing.T) {
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "repo", "skill", "foo")
+	os.MkdirAll(filepath.Join(source, "scripts"), 0755)
+	os.WriteFile(filepath.Join(source, "SKILL.md"), []byte("v1"), 0644)
+	os.WriteFile(filepath.Join(source, "scripts", "run.sh"), []byte("echo"), 0644)
+	target := filepath.Join(tmp, "platform", "skills", "foo")
+
+	if err := Distribute(source, target, LinkModeCopy); err != nil {
+		t.Fatalf("Distribute failed: %v", err)
+	}
+	if IsSymlink(target) || !IsManagedCopy(target) || !IsDistributed(target) {
+		t.Fatal("expected a managed copy")
+	}
+	if state, _ := CopyState(source, target); state != CopyStateOK {
+		t.Errorf("expected %s, got %s", CopyStateOK, state)
+	}
+
+	// 源变更
+	os.WriteFile(filepath.Join(source, "SKILL.md"), []byte("v2"), 0644)
+	if state, _ := CopyState(source, target); state != CopyStateOutdated {
+		t.Errorf("expected %s, got %s", CopyStateOutdated, state)
+	}
+
+	// 重新分发覆盖未修改的副本
+	if err := Distribute(source, target, LinkModeCopy); err != nil {
+		t.Fatalf("re-Distribute failed: %v", err)
+	}
+	if state, _ := CopyState(source, target); state != CopyStateOK {
+		t.Errorf("expected %s after refresh, got %s", CopyStateOK, state)
+	}
+
+	// 本地修改的副本不会被覆盖或删除
+	os.WriteFile(filepath.Join(target, "SKILL.md"), []byte("edited"), 0644)
+	if state, _ := CopyState(source, target); state != CopyStateModified {
+		t.Errorf("expected %s, got %s", CopyStateModified, state)
+	}
+	if err := Distribute(source, target, LinkModeCopy); err == nil {
+		t.Error("expected error overwriting a modified copy")
+	}
+	if err := Undistribute(target); err == nil {
+		t.Error("expected error removing a modified copy")
+	}
+
+	os.WriteFile(filepath.Join(target, "SKILL.md"), []byte("v2"), 0644)
+	if err := Undistribute(target); err != nil {
+		t.Fatalf("Undistribute failed: %v", err)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Error("expected copy to be removed")
+	}
+}
+
+func TestDistributeHardlink(t *testing.T) {
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "foo")
+	os.MkdirAll(source, 0755)
+	os.WriteFile(filepath.Join(source, "SKILL.md"), []byte("v1"), 0644)
+	target := filepath.Join(tmp, "skills", "foo")
+
+	if err := Distribute(source, target, LinkModeHardlink); err != nil {
+		t.Fatalf("Distribute failed: %v", err)
+	}
+	marker, err := ReadCopyMarker(target)
+	if err != nil || marker.Mode != LinkModeHardlink {
+		t.Fatalf("expected hardlink marker, got %+v, %v", marker, err)
+	}
+
+	srcInfo, _ := os.Stat(filepath.Join(source, "SKILL.md"))
+	dstInfo, _ := os.Stat(filepath.Join(target, "SKILL.md"))
+	if !os.SameFile(srcInfo, dstInfo) {
+		t.Error("expected SKILL.md to be hard linked")
+	}
+
+	// 原地编辑源文件，副本随之变化，不算本地修改
+	f, _ := os.OpenFile(filepath.Join(source, "SKILL.md"), os.O_WRONLY|os.O_TRUNC, 0644)
+	f.WriteString("v2")
+	f.Close()
+	if state, err := CopyState(source, target); err != nil || state != CopyStateOK {
+		t.Errorf("expected state ok after in-place edit of the source, got %s, %v", state, err)
+	}
+
+	// 源中新增文件，副本过期
+	os.WriteFile(filepath.Join(source, "extra.md"), []byte("x"), 0644)
+	if state, _ := CopyState(source, target); state != CopyStateOutdated {
+		t.Errorf("expected state outdated after adding a source file, got %s", state)
+	}
+	if !isReplaceableCopy(target) {
+		t.Error("hard linked copy following the source should be replaceable")
+	}
+
+	// 副本中不再与源共享的文件才是本地修改
+	os.Remove(filepath.Join(target, "SKILL.md"))
+	os.WriteFile(filepath.Join(target, "SKILL.md"), []byte("local"), 0644)
+	if state, _ := CopyState(source, target); state != CopyStateModified {
+		t.Errorf("expected state modified after a local edit, got %s", state)
+	}
+	if err := Undistribute(target); err == nil {
+		t.Error("expected Undistribute to refuse a modified hard linked copy")
+	}
+}
+
+func TestUndistributeRefusesUnmanagedDir(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "foo")
+	os.MkdirAll(target, 0755)
+	os.WriteFile(filepath.Join(target, "SKILL.md"), []byte("mine"), 0644)
+
+	if err := Undistribute(target); err == nil {
+		t.Error("expected error for unmanaged directory")
+	}
+	if err := Distribute(t.TempDir(), target, LinkModeCopy); err == nil {
+		t.Error("expected error overwriting unmanaged directory")
+	}
+	if _, err := os.Stat(filepath.Join(target, "SKILL.md")); err != nil {
+		t.Error("unmanaged directory must be left intact")
+	}
+}
@@ -0,0 +1,235 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 链接问题类型
+const (
+	IssueBroken = "broken" // 模块的链接指向其他位置或已失效
+	IssueStale  = "stale"  // 模块的链接指向该模块的旧路径（如仓库迁移后）
+	IssueOrphan = "orphan" // 指向仓库内、但不属于任何模块当前链接名的软链接
+)
+
+// 修复方式
+const (
+	FixRepoint = "repoint" // 重新指向模块
+	FixDelete  = "delete"  // 删除软链接
+	FixAdopt   = "adopt"   // 将链接名写入模块的 skillkit.toml 覆盖配置
+)
+
+// LinkIssue 单个链接问题
+type LinkIssue struct {
+	Kind       string   `json:"kind"`
+	Scope      string   `json:"scope"`
+	Platform   string   `json:"platform"`
+	Target     string   `json:"target"`           // 软链接路径
+	PointsTo   string   `json:"points_to"`        // 软链接的指向（已解析为绝对路径）
+	ModuleName string   `json:"module,omitempty"` // 相关模块名
+	Module     *Module  `json:"-"`                // 相关模块，orphan 指向已删除模块时为 nil
+	Fixes      []string `json:"fixes"`            // 可用修复，第一个为默认修复
+}
+
+// linkClaim 某个目标路径应有的链接
+type linkClaim struct {
+	mod      *Module
+	platform string
+}
+
+// scanDir 需要扫描的平台目录
+type scanDir struct {
+	path     string
+	scope    string
+	platform string // 使用该目录的第一个平台 key
+}
+
+// Diagnose 检查平台技能/代理目录中的软链接，返回问题列表
+// 未指定作用域时检查全局和当前项目
+func Diagnose(cfg *Config, modules []*Module, scopes ...string) ([]LinkIssue, error) {
+	if len(scopes) == 0 {
+		scopes = AllScopes
+	}
+
+	claims := make(map[string]linkClaim)
+	byPath := make(map[string]*Module)
+	for _, mod := range modules {
+		byPath[mod.Path] = mod
+	}
+
+	keys := make([]string, 0, len(cfg.Platforms))
+	for key := range cfg.Platforms {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// 项目目录可能与全局目录相同（如在 home 下运行），按路径去重，全局优先
+	var dirs []scanDir
+	seenDirs := make(map[string]bool)
+	for _, scope := range scopes {
+		for _, key := range keys {
+			p := cfg.Platforms[key]
+			if p.GetBaseDir(scope) == "" {
+				continue
+			}
+			for _, category := range []string{"skill", "agent"} {
+				dir := p.GetTargetDir(scope, category)
+				if !seenDirs[dir] {
+					seenDirs[dir] = true
+					dirs = append(dirs, scanDir{path: dir, scope: scope, platform: key})
+				}
+				for _, mod := range modules {
+					if mod.Category != category {
+						continue
+					}
+					target := filepath.Join(dir, p.LinkName(key, mod, scope))
+					if _, ok := claims[target]; !ok {
+						claims[target] = linkClaim{mod: mod, platform: key}
+					}
+				}
+			}
+		}
+	}
+
+	var issues []LinkIssue
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir.path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			target := filepath.Join(dir.path, entry.Name())
+			if !IsSymlink(target) {
+				continue
+			}
+			dest, err := resolveLinkDest(target)
+			if err != nil {
+				continue
+			}
+
+			if claim, ok := claims[target]; ok {
+				if dest == claim.mod.Path {
+					continue
+				}
+				kind := IssueBroken
+				if filepath.Base(dest) == filepath.Base(claim.mod.Path) {
+					kind = IssueStale
+				}
+				issues = append(issues, LinkIssue{
+					Kind:       kind,
+					Scope:      dir.scope,
+					Platform:   claim.platform,
+					Target:     target,
+					PointsTo:   dest,
+					ModuleName: claim.mod.Name,
+					Module:     claim.mod,
+					Fixes:      []string{FixRepoint, FixDelete},
+				})
+				continue
+			}
+
+			if !isWithin(dest, cfg.RepoPath) {
+				continue
+			}
+			issue := LinkIssue{
+				Kind:     IssueOrphan,
+				Scope:    dir.scope,
+				Platform: dir.platform,
+				Target:   target,
+				PointsTo: dest,
+				Module:   byPath[dest],
+				Fixes:    []string{FixDelete},
+			}
+			if issue.Module != nil {
+				issue.ModuleName = issue.Module.Name
+				// 别名对全局和项目同时生效，模块在该平台上尚未以当前名称链接时才能收编
+				if !isLinkedOnPlatform(cfg.Platforms[dir.platform], dir.platform, issue.Module) {
+					issue.Fixes = []string{FixAdopt, FixDelete}
+				}
+			}
+			issues = append(issues, issue)
+		}
+	}
+	return issues, nil
+}
+
+// ScanOrphans 列出指向仓库、但不属于任何模块当前链接名的软链接
+// 未指定作用域时检查全局和当前项目
+func ScanOrphans(cfg *Config, modules []*Module, scopes ...string) ([]LinkIssue, error) {
+	issues, err := Diagnose(cfg, modules, scopes...)
+	if err != nil {
+		return nil, err
+	}
+	var orphans []LinkIssue
+	for _, issue := range issues {
+		if issue.Kind == IssueOrphan {
+			orphans = append(orphans, issue)
+		}
+	}
+	return orphans, nil
+}
+
+// ApplyFix 对问题执行指定修复
+func ApplyFix(cfg *Config, issue LinkIssue, fix string) error {
+	switch fix {
+	case FixRepoint:
+		if issue.Module == nil {
+			return fmt.Errorf("no module to point %s at", issue.Target)
+		}
+		return Distribute(issue.Module.Path, issue.Target, cfg.Platforms[issue.Platform].GetLinkMode())
+	case FixDelete:
+		if !IsSymlink(issue.Target) {
+			return fmt.Errorf("target is not a symlink: %s", issue.Target)
+		}
+		return os.Remove(issue.Target)
+	case FixAdopt:
+		if issue.Module == nil {
+			return fmt.Errorf("no module to adopt %s", issue.Target)
+		}
+		modCfg, err := LoadModuleConfig(issue.Module.Path)
+		if err != nil {
+			return err
+		}
+		if modCfg.Link.Overrides == nil {
+			modCfg.Link.Overrides = make(map[string]string)
+		}
+		modCfg.Link.Overrides[issue.Platform] = filepath.Base(issue.Target)
+		return SaveModuleConfig(issue.Module.Path, modCfg)
+	}
+	return fmt.Errorf("unknown fix: %s", fix)
+}
+
+// isLinkedOnPlatform 检查模块是否已以当前链接名分发到平台的全局或项目目录
+func isLinkedOnPlatform(p Platform, key string, mod *Module) bool {
+	for _, scope := range AllScopes {
+		if target := ModuleTarget(p, key, mod, scope); target != "" && IsDistributed(target) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveLinkDest 读取软链接指向，相对路径按链接所在目录解析
+func resolveLinkDest(link string) (string, error) {
+	dest, err := os.Readlink(link)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(dest) {
+		dest = filepath.Join(filepath.Dir(link), dest)
+	}
+	return filepath.Clean(dest), nil
+}
+
+// isWithin 检查 path 是否位于 root 之下
+func isWithin(path, root string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
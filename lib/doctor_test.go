@@ -0,0 +1,141 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiagnoseAndFix(t *testing.T) {
+	tmp := t.TempDir()
+	cfg := &Config{
+		RepoPath: filepath.Join(tmp, "repo"),
+		Platforms: map[string]Platform{
+			"claude": {Global: filepath.Join(tmp, "claude"), SkillDir: "skills", AgentDir: "agents"},
+		},
+	}
+	for _, name := range []string{"foo", "bar", "baz"} {
+		dir := filepath.Join(cfg.RepoPath, "skill", name)
+		os.MkdirAll(dir, 0755)
+		os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte(name), 0644)
+	}
+	modules, _ := ListModules(cfg)
+
+	skills := filepath.Join(tmp, "claude", "skills")
+	os.MkdirAll(skills, 0755)
+	os.Symlink(filepath.Join(cfg.RepoPath, "skill", "foo"), filepath.Join(skills, "foo")) // 正常
+	os.Symlink("/old/repo/skill/bar", filepath.Join(skills, "bar"))                       // 旧路径
+	os.Symlink("/somewhere/else", filepath.Join(skills, "baz"))                           // 指向其他位置
+	os.Symlink(filepath.Join(cfg.RepoPath, "skill", "foo"), filepath.Join(skills, "foo-old"))
+	os.Symlink(filepath.Join(cfg.RepoPath, "skill", "gone"), filepath.Join(skills, "gone"))
+	os.Symlink("/unrelated", filepath.Join(skills, "unrelated")) // 仓库外，不处理
+
+	issues, err := Diagnose(cfg, modules)
+	if err != nil {
+		t.Fatalf("Diagnose failed: %v", err)
+	}
+
+	byTarget := make(map[string]LinkIssue)
+	for _, issue := range issues {
+		byTarget[filepath.Base(issue.Target)] = issue
+	}
+	expected := map[string]struct{ kind, fix string }{
+		"bar":     {IssueStale, FixRepoint},
+		"baz":     {IssueBroken, FixRepoint},
+		"foo-old": {IssueOrphan, FixDelete}, // foo 已链接，不能收编
+		"gone":    {IssueOrphan, FixDelete},
+	}
+	if len(issues) != len(expected) {
+		t.Fatalf("expected %d issues, got %d: %+v", len(expected), len(issues), issues)
+	}
+	for name, e := range expected {
+		issue, ok := byTarget[name]
+		if !ok || issue.Kind != e.kind || issue.Fixes[0] != e.fix {
+			t.Errorf("%s: expected %s/%s, got %+v", name, e.kind, e.fix, issue)
+		}
+	}
+
+	for _, issue := range issues {
+		if err := ApplyFix(cfg, issue, issue.Fixes[0]); err != nil {
+			t.Errorf("ApplyFix(%s) failed: %v", issue.Target, err)
+		}
+	}
+	issues, _ = Diagnose(cfg, modules)
+	if len(issues) != 0 {
+		t.Errorf("expected no issues after fixing, got %+v", issues)
+	}
+}
+
+func TestDiagnoseAdopt(t *testing.T) {
+	tmp := t.TempDir()
+	cfg := &Config{
+		RepoPath: filepath.Join(tmp, "repo"),
+		Platforms: map[string]Platform{
+			"cursor": {Global: filepath.Join(tmp, "cursor"), SkillDir: "skills"},
+		},
+	}
+	modDir := filepath.Join(cfg.RepoPath, "skill", "python")
+	os.MkdirAll(modDir, 0755)
+	modules, _ := ListModules(cfg)
+
+	// 别名从 py-coder 改回默认名后，旧链接仍存在
+	skills := filepath.Join(tmp, "cursor", "skills")
+	os.MkdirAll(skills, 0755)
+	os.Symlink(modDir, filepath.Join(skills, "py-coder"))
+
+	issues, _ := Diagnose(cfg, modules)
+	if len(issues) != 1 || issues[0].Fixes[0] != FixAdopt {
+		t.Fatalf("expected one adoptable orphan, got %+v", issues)
+	}
+	if err := ApplyFix(cfg, issues[0], FixAdopt); err != nil {
+		t.Fatalf("ApplyFix adopt failed: %v", err)
+	}
+
+	mod, _ := FindModule(cfg, "python")
+	if mod.GetLinkName("cursor") != "py-coder" {
+		t.Errorf("expected adopted alias py-coder, got %s", mod.GetLinkName("cursor"))
+	}
+	modules, _ = ListModules(cfg)
+	if issues, _ := Diagnose(cfg, modules); len(issues) != 0 {
+		t.Errorf("expected no issues after adopting, got %+v", issues)
+	}
+}
+
+func TestScanOrphansProjectScope(t *testing.T) {
+	tmp := t.TempDir()
+	project := filepath.Join(tmp, "project")
+	os.MkdirAll(project, 0755)
+	t.Chdir(project)
+
+	cfg := &Config{
+		RepoPath: filepath.Join(tmp, "repo"),
+		Platforms: map[string]Platform{
+			"claude": {Global: filepath.Join(tmp, "home", ".claude"), Project: ".claude/", SkillDir: "skills", AgentDir: "agents"},
+		},
+	}
+	modDir := filepath.Join(cfg.RepoPath, "agent", "reviewer")
+	os.MkdirAll(modDir, 0755)
+	modules, _ := ListModules(cfg)
+
+	// 项目中的正常链接和遗留链接
+	agents := filepath.Join(project, ".claude", "agents")
+	os.MkdirAll(agents, 0755)
+	os.Symlink(modDir, filepath.Join(agents, "reviewer"))
+	os.Symlink(modDir, filepath.Join(agents, "old-reviewer"))
+
+	orphans, err := ScanOrphans(cfg, modules)
+	if err != nil {
+		t.Fatalf("ScanOrphans failed: %v", err)
+	}
+	if len(orphans) != 1 {
+		t.Fatalf("expected 1 orphan, got %+v", orphans)
+	}
+	o := orphans[0]
+	if o.Scope != ScopeProject || o.ModuleName != "reviewer" || filepath.Base(o.Target) != "old-reviewer" {
+		t.Errorf("unexpected orphan: %+v", o)
+	}
+	// 模块已在项目中链接，不能收编
+	if o.Fixes[0] != FixDelete {
+		t.Errorf("expected delete as default fix, got %v", o.Fixes)
+	}
+}
@@ -14,11 +14,12 @@ import (
 
 // ParsedSource 解析后的源地址
 type ParsedSource struct {
-	Type      string // github, gitlab, git, local
-	URL       string // Git URL
+	Type      string // github, gitlab, gitea, bitbucket, git, local, archive
+	URL       string // Git URL 或归档地址
 	Ref       string // 分支/标签
 	Subpath   string // 仓库内子路径
-	LocalPath string // 本地路径
+	LocalPath string // 本地路径（本地目录或本地归档）
+	SHA256    string // 归档的期望 SHA-256（为空时不校验）
 }
 
 // DiscoveredSkill 发现的技能
@@ -28,18 +29,40 @@ type DiscoveredSkill struct {
 	Path        string
 	Category    string // skill 或 agent
 	Hash        string
+	Manifest    *SkillManifest
 }
 
-// ParseSource 解析源地址字符串
+// ParseSource 使用内置托管平台解析源地址字符串，见 ParseSourceWith
+func ParseSource(input string) *ParsedSource {
+	return ParseSourceWith(input, nil)
+}
+
+// ParseSourceWith 解析源地址字符串，hosts 为托管平台注册表（nil 时只有内置平台）
 // 支持格式:
 //   - 本地路径: ./path, ../path, /absolute/path
 //   - GitHub URL: https://github.com/owner/repo
 //   - GitHub URL with branch: https://github.com/owner/repo/tree/branch
 //   - GitHub URL with path: https://github.com/owner/repo/tree/branch/path/to/skill
 //   - GitHub shorthand: owner/repo, owner/repo/path/to/skill
-//   - GitLab URL: https://gitlab.com/owner/repo
+//   - GitLab URL: https://gitlab.com/group/subgroup/repo/-/tree/branch/path
+//   - Gitea URL: https://codeberg.org/owner/repo/src/branch/main/path
+//   - Bitbucket URL: https://bitbucket.org/workspace/repo/src/main/path
+//   - 注册的自建平台（GitHub Enterprise, GitLab, Gitea, Bitbucket Server）的同类地址
+//   - 指向文件的地址（blob、SKILL.md）取文件所在目录
 //   - Direct git URL: git@github.com:owner/repo.git
-func ParseSource(input string) *ParsedSource {
+//   - 以上远程格式均可追加 @<ref> 指定分支、标签或提交: owner/repo@v1.2.0
+//   - 归档: https://example.com/skills.zip, ./skills.tar.gz（.zip, .tar.gz, .tgz, .tar）
+func ParseSourceWith(input string, hosts *HostRegistry) *ParsedSource {
+	// 归档（远程地址或本地文件）
+	if isArchiveSource(input) {
+		parsed := &ParsedSource{Type: "archive", URL: input}
+		if isLocalPath(input) {
+			parsed.URL, _ = filepath.Abs(input)
+			parsed.LocalPath = parsed.URL
+		}
+		return parsed
+	}
+
 	// 本地路径
 	if isLocalPath(input) {
 		absPath, _ := filepath.Abs(input)
@@ -50,56 +73,18 @@ func ParseSource(input string) *ParsedSource {
 		}
 	}
 
-	// GitHub URL with path: https://github.com/owner/repo/tree/branch/path
-	githubTreeWithPath := regexp.MustCompile(`github\.com/([^/]+)/([^/]+)/tree/([^/]+)/(.+)`)
-	if m := githubTreeWithPath.FindStringSubmatch(input); m != nil {
-		return &ParsedSource{
-			Type:    "github",
-			URL:     fmt.Sprintf("https://github.com/%s/%s.git", m[1], m[2]),
-			Ref:     m[3],
-			Subpath: m[4],
-		}
-	}
-
-	// GitHub URL with branch only: https://github.com/owner/repo/tree/branch
-	githubTree := regexp.MustCompile(`github\.com/([^/]+)/([^/]+)/tree/([^/]+)$`)
-	if m := githubTree.FindStringSubmatch(input); m != nil {
-		return &ParsedSource{
-			Type: "github",
-			URL:  fmt.Sprintf("https://github.com/%s/%s.git", m[1], m[2]),
-			Ref:  m[3],
-		}
-	}
-
-	// GitHub URL: https://github.com/owner/repo
-	githubRepo := regexp.MustCompile(`github\.com/([^/]+)/([^/]+?)(?:\.git)?$`)
-	if m := githubRepo.FindStringSubmatch(input); m != nil {
-		repo := strings.TrimSuffix(m[2], ".git")
-		return &ParsedSource{
-			Type: "github",
-			URL:  fmt.Sprintf("https://github.com/%s/%s.git", m[1], repo),
-		}
-	}
-
-	// GitLab URL with path: https://gitlab.com/owner/repo/-/tree/branch/path
-	gitlabTreeWithPath := regexp.MustCompile(`gitlab\.com/([^/]+)/([^/]+)/-/tree/([^/]+)/(.+)`)
-	if m := gitlabTreeWithPath.FindStringSubmatch(input); m != nil {
-		return &ParsedSource{
-			Type:    "gitlab",
-			URL:     fmt.Sprintf("https://gitlab.com/%s/%s.git", m[1], m[2]),
-			Ref:     m[3],
-			Subpath: m[4],
+	// 末尾的 @<ref>
+	if base, ref := splitRefSuffix(input); ref != "" {
+		parsed := ParseSourceWith(base, hosts)
+		if parsed.Type != "local" && parsed.Type != "archive" {
+			parsed.Ref = ref
 		}
+		return parsed
 	}
 
-	// GitLab URL: https://gitlab.com/owner/repo
-	gitlabRepo := regexp.MustCompile(`gitlab\.com/([^/]+)/([^/]+?)(?:\.git)?$`)
-	if m := gitlabRepo.FindStringSubmatch(input); m != nil {
-		repo := strings.TrimSuffix(m[2], ".git")
-		return &ParsedSource{
-			Type: "gitlab",
-			URL:  fmt.Sprintf("https://gitlab.com/%s/%s.git", m[1], repo),
-		}
+	// 注册的托管平台地址
+	if parsed := parseHostURL(input, hosts); parsed != nil {
+		return parsed
 	}
 
 	// GitHub shorthand: owner/repo 或 owner/repo/path/to/skill
@@ -122,6 +107,33 @@ func ParseSource(input string) *ParsedSource {
 	}
 }
 
+// splitRefSuffix 拆分末尾的 @<ref>
+// 仅当 @ 位于最后一个 / 之后时生效，避免误伤 git@host:owner/repo 形式的 SSH 地址
+func splitRefSuffix(input string) (string, string) {
+	idx := strings.LastIndex(input, "@")
+	if idx <= 0 || idx < strings.LastIndex(input, "/") || idx == len(input)-1 {
+		return input, ""
+	}
+	ref := input[idx+1:]
+	if strings.Contains(ref, ":") {
+		return input, ""
+	}
+	return input[:idx], ref
+}
+
+// IsCommitSHA 判断 ref 是否形如提交 SHA（7-40 位十六进制）
+func IsCommitSHA(ref string) bool {
+	if len(ref) < 7 || len(ref) > 40 {
+		return false
+	}
+	for _, c := range ref {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
 func isLocalPath(input string) bool {
 	if filepath.IsAbs(input) {
 		return true
@@ -135,29 +147,187 @@ func isLocalPath(input string) bool {
 	return false
 }
 
+// FetchedSource 已获取到本地的源
+type FetchedSource struct {
+	Dir     string // 源根目录（本地路径或临时克隆目录）
+	Commit  string // 解析到的提交 SHA（本地源为空）
+	SHA256  string // 归档文件的 SHA-256（仅归档源）
+	tempDir string
+}
+
+// Cleanup 清理临时克隆目录（本地源不做任何操作）
+func (f *FetchedSource) Cleanup() {
+	if f.tempDir != "" {
+		CleanupTempDir(f.tempDir)
+	}
+}
+
+// FetchSource 获取源内容：本地源直接使用，归档解压到临时目录，远程源克隆到临时目录
+// 启用缓存时先增量获取到 ~/.config/agent/.cache/git，缓存不可用时回退为直接克隆。
+func FetchSource(cfg *Config, parsed *ParsedSource) (*FetchedSource, error) {
+	if parsed.Type == "local" {
+		if _, err := os.Stat(parsed.LocalPath); err != nil {
+			return nil, fmt.Errorf("path not found: %s", parsed.LocalPath)
+		}
+		return &FetchedSource{Dir: parsed.LocalPath}, nil
+	}
+	if parsed.Type == "archive" {
+		return fetchArchive(cfg, parsed)
+	}
+
+	if cfg != nil && cfg.Offline {
+		return fetchOffline(cfg, parsed)
+	}
+
+	auth, err := ResolveAuth(cfg, parsed.URL)
+	if err != nil {
+		return nil, err
+	}
+	var tempDir string
+	if cfg != nil && !cfg.Cache.Disabled {
+		tempDir, err = cloneViaCache(cfg, auth, parsed.URL, parsed.Ref)
+	}
+	if tempDir == "" && !IsAuthError(err) {
+		tempDir, err = cloneRepo(auth, parsed.URL, parsed.Ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+	commit, _ := ResolveCommit(tempDir)
+	return &FetchedSource{Dir: tempDir, Commit: commit, tempDir: tempDir}, nil
+}
+
+// fetchOffline 离线获取：优先使用 git 缓存，其次使用按提交命名的归档
+func fetchOffline(cfg *Config, parsed *ParsedSource) (*FetchedSource, error) {
+	cacheDir := filepath.Join(CacheRoot(cfg), CacheKey(parsed.URL))
+	if tempDir, err := cloneFromCache(cacheDir, parsed.Ref); err == nil {
+		commit, _ := ResolveCommit(tempDir)
+		return &FetchedSource{Dir: tempDir, Commit: commit, tempDir: tempDir}, nil
+	}
+
+	if IsCommitSHA(parsed.Ref) {
+		if archive := FindArchive(cfg.ArchiveDirs, parsed.Ref); archive != "" {
+			tempDir, err := ExtractArchive(archive)
+			if err != nil {
+				return nil, err
+			}
+			return &FetchedSource{Dir: tempDir, Commit: parsed.Ref, tempDir: tempDir}, nil
+		}
+	}
+
+	source := Redact(parsed.URL)
+	if parsed.Ref != "" {
+		source += "@" + parsed.Ref
+	}
+	return nil, &OfflineError{Source: source}
+}
+
 // CloneRepo 克隆仓库到临时目录
+// ref 可以是分支、标签或提交 SHA；提交 SHA 会先单独获取该提交再检出。
 func CloneRepo(url string, ref string) (string, error) {
+	return cloneRepo(nil, url, ref)
+}
+
+func cloneRepo(auth *GitAuth, url string, ref string) (string, error) {
 	tempDir, err := os.MkdirTemp("", "skillkit-")
 	if err != nil {
 		return "", fmt.Errorf("failed to create temp directory: %w", err)
 	}
 
+	// 形如短 SHA 的 ref 也可能是分支或标签，远程存在同名分支/标签时按分支/标签克隆
+	if IsCommitSHA(ref) && (len(ref) == 40 || !isRemoteRef(auth, url, ref)) {
+		if err := cloneCommit(auth, url, ref, tempDir); err != nil {
+			os.RemoveAll(tempDir)
+			return "", err
+		}
+		return tempDir, nil
+	}
+
 	args := []string{"clone", "--depth", "1"}
 	if ref != "" {
 		args = append(args, "--branch", ref)
 	}
 	args = append(args, url, tempDir)
 
-	cmd := exec.Command("git", args...)
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	if err := runGit(auth, url, args...); err != nil {
 		os.RemoveAll(tempDir)
-		return "", fmt.Errorf("git clone failed: %w", err)
+		return "", gitError("git clone failed", err)
 	}
 
 	return tempDir, nil
 }
 
+// cloneCommit 检出指定提交
+// 完整 SHA 优先按提交浅获取；服务端不支持或为短 SHA 时回退到完整克隆后检出。
+func cloneCommit(auth *GitAuth, url, commit, dir string) error {
+	if len(commit) == 40 {
+		steps := [][]string{
+			{"init", "--quiet", dir},
+			{"-C", dir, "remote", "add", "origin", url},
+			{"-C", dir, "fetch", "--quiet", "--depth", "1", "origin", commit},
+			{"-C", dir, "checkout", "--quiet", "--detach", "FETCH_HEAD"},
+		}
+		err := runGitSteps(auth, url, steps)
+		if err == nil || IsAuthError(err) {
+			return err
+		}
+		// 清空目录后回退
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			os.RemoveAll(filepath.Join(dir, e.Name()))
+		}
+	}
+
+	steps := [][]string{
+		{"clone", "--no-checkout", url, dir},
+		{"-C", dir, "checkout", "--quiet", "--detach", commit},
+	}
+	if err := runGitSteps(auth, url, steps); err != nil {
+		return gitError("git checkout of commit "+commit+" failed", err)
+	}
+	return nil
+}
+
+// isRemoteRef 检查 ref 是否为远程仓库的分支或标签
+func isRemoteRef(auth *GitAuth, url, ref string) bool {
+	cmd := exec.Command("git", "ls-remote", "--heads", "--tags", url, ref)
+	cmd.Env = gitEnv(auth, url)
+	out, err := cmd.Output()
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		name := strings.TrimSuffix(fields[1], "^{}")
+		if name == "refs/heads/"+ref || name == "refs/tags/"+ref {
+			return true
+		}
+	}
+	return false
+}
+
+// runGitSteps 依次执行 git 命令，remote 为访问的远程地址（本地操作传空）
+func runGitSteps(auth *GitAuth, remote string, steps [][]string) error {
+	for _, args := range steps {
+		if err := runGit(auth, remote, append([]string{"-c", "advice.detachedHead=false"}, args...)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResolveCommit 解析仓库当前检出的提交 SHA
+func ResolveCommit(dir string) (string, error) {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
 // CleanupTempDir 清理临时目录
 func CleanupTempDir(dir string) error {
 	// 安全检查：确保是临时目录
@@ -187,6 +357,11 @@ func DiscoverSkills(basePath string, subpath string) ([]*DiscoveredSkill, error)
 		}
 	}
 
+	// 搜索目录本身无法读取时报错，而不是当作没有技能
+	if _, err := os.ReadDir(searchPath); err != nil {
+		return nil, err
+	}
+
 	// 搜索常见位置
 	priorityDirs := []string{
 		searchPath,
@@ -263,8 +438,9 @@ func parseSkillFile(dir string) *DiscoveredSkill {
 
 	hash := hashContent(content)
 
-	// 解析 frontmatter
-	name, description := parseFrontmatter(string(content))
+	// 解析 frontmatter，YAML 有误时仍使用能读到的部分
+	manifest, _ := ParseSkillManifest(content)
+	name := manifest.Name
 	if name == "" {
 		// 使用目录名作为名称
 		name = filepath.Base(dir)
@@ -272,39 +448,14 @@ func parseSkillFile(dir string) *DiscoveredSkill {
 
 	return &DiscoveredSkill{
 		Name:        name,
-		Description: description,
+		Description: manifest.Description,
 		Path:        dir,
 		Category:    category,
 		Hash:        hash,
+		Manifest:    manifest,
 	}
 }
 
-func parseFrontmatter(content string) (name, description string) {
-	lines := strings.Split(content, "\n")
-	inFrontmatter := false
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "---" {
-			if inFrontmatter {
-				break
-			}
-			inFrontmatter = true
-			continue
-		}
-		if inFrontmatter {
-			if strings.HasPrefix(line, "name:") {
-				name = strings.TrimSpace(strings.TrimPrefix(line, "name:"))
-				name = strings.Trim(name, `"'`)
-			} else if strings.HasPrefix(line, "description:") {
-				description = strings.TrimSpace(strings.TrimPrefix(line, "description:"))
-				description = strings.Trim(description, `"'`)
-			}
-		}
-	}
-	return
-}
-
 func findSkillsRecursive(dir string, seen map[string]bool, depth, maxDepth int) []*DiscoveredSkill {
 	if depth > maxDepth {
 		return nil
@@ -356,8 +507,60 @@ func hashContent(data []byte) string {
 	return hex.EncodeToString(sum[:])
 }
 
+// HashDir 计算目录内容哈希（相对路径 + 文件内容，按路径排序，忽略 .git、复制标记和 vendor 记录文件）
+func HashDir(dir string) (string, error) {
+	return hashDir(dir, nil)
+}
+
+// hashDir 计算目录内容哈希，skip 中的相对路径不参与计算
+func hashDir(dir string, skip map[string]bool) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == CopyMarkerName || d.Name() == VendorMarkerName {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if skip[filepath.ToSlash(rel)] {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		h.Write([]byte(filepath.ToSlash(rel)))
+		h.Write([]byte{0})
+		h.Write([]byte(hashContent(data)))
+		h.Write([]byte{'\n'})
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 // InstallSkill 安装技能到本地仓库
+// 名称可能来自下载内容中的 frontmatter，须为单个目录名
 func InstallSkill(skill *DiscoveredSkill, cfg *Config) error {
+	if err := ValidateDirName(skill.Name); err != nil {
+		return err
+	}
+	if err := ValidateCategory(skill.Category); err != nil {
+		return err
+	}
+
 	// 目标目录
 	targetBase := filepath.Join(cfg.RepoPath, skill.Category)
 	targetDir := filepath.Join(targetBase, skill.Name)
@@ -411,8 +614,13 @@ func copyDir(src, dst string) error {
 	return nil
 }
 
-// SelectSkillsInteractive 交互式选择技能
+// SelectSkillsInteractive 交互式选择技能（默认全选）
 func SelectSkillsInteractive(skills []*DiscoveredSkill) []*DiscoveredSkill {
+	return SelectSkills(skills, true)
+}
+
+// SelectSkills 交互式选择技能，preselect 为 false 时默认不选（如搜索结果）
+func SelectSkills(skills []*DiscoveredSkill, preselect bool) []*DiscoveredSkill {
 	if len(skills) == 0 {
 		return nil
 	}
@@ -438,7 +646,7 @@ func SelectSkillsInteractive(skills []*DiscoveredSkill) []*DiscoveredSkill {
 
 	selected := make([]bool, len(skills))
 	for i := range selected {
-		selected[i] = true // 默认全选
+		selected[i] = preselect
 	}
 
 	cursor := 0
@@ -0,0 +1,150 @@
+package lib
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSourceRefSuffix(t *testing.T) {
+	tests := []struct {
+		input   string
+		url     string
+		ref     string
+		subpath string
+	}{
+		{"owner/repo@v1.2.0", "https://github.com/owner/repo.git", "v1.2.0", ""},
+		{"owner/repo/skills/x@main", "https://github.com/owner/repo.git", "main", "skills/x"},
+		{"https://github.com/owner/repo@3f1c2a9", "https://github.com/owner/repo.git", "3f1c2a9", ""},
+		{"git@github.com:owner/repo.git", "git@github.com:owner/repo.git", "", ""},
+		{"ssh://git@example.com/owner/repo.git", "ssh://git@example.com/owner/repo.git", "", ""},
+	}
+
+	for _, tt := range tests {
+		parsed := ParseSource(tt.input)
+		if parsed.URL != tt.url || parsed.Ref != tt.ref || parsed.Subpath != tt.subpath {
+			t.Errorf("ParseSource(%s) = {URL:%s Ref:%s Subpath:%s}, expected {URL:%s Ref:%s Subpath:%s}",
+				tt.input, parsed.URL, parsed.Ref, parsed.Subpath, tt.url, tt.ref, tt.subpath)
+		}
+	}
+}
+
+func TestIsCommitSHA(t *testing.T) {
+	for _, ref := range []string{"3f1c2a9", "3F1C2A9D", strings.Repeat("a", 40)} {
+		if !IsCommitSHA(ref) {
+			t.Errorf("IsCommitSHA(%s) should be true", ref)
+		}
+	}
+	for _, ref := range []string{"main", "v1.2.0", "abc", strings.Repeat("a", 41), "3f1c2ag"} {
+		if IsCommitSHA(ref) {
+			t.Errorf("IsCommitSHA(%s) should be false", ref)
+		}
+	}
+}
+
+// initTestRepo 创建包含两个提交的本地仓库，返回仓库 URL 和第一个提交
+func initTestRepo(t *testing.T) (string, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	git("init", "--quiet")
+	os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte("v1"), 0644)
+	git("add", "-A")
+	git("commit", "--quiet", "-m", "v1")
+	first := git("rev-parse", "HEAD")
+	os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte("v2"), 0644)
+	git("commit", "--quiet", "-am", "v2")
+
+	return "file://" + dir, first
+}
+
+func TestCloneRepoAtCommit(t *testing.T) {
+	url, first := initTestRepo(t)
+
+	for _, ref := range []string{first, first[:8]} {
+		dir, err := CloneRepo(url, ref)
+		if err != nil {
+			t.Fatalf("CloneRepo(%s) failed: %v", ref, err)
+		}
+		defer os.RemoveAll(dir)
+
+		commit, err := ResolveCommit(dir)
+		if err != nil {
+			t.Fatalf("ResolveCommit failed: %v", err)
+		}
+		if commit != first {
+			t.Errorf("CloneRepo(%s) checked out %s, expected %s", ref, commit, first)
+		}
+		data, _ := os.ReadFile(filepath.Join(dir, "SKILL.md"))
+		if string(data) != "v1" {
+			t.Errorf("expected content of first commit, got %q", data)
+		}
+	}
+}
+
+func TestFetchSourceHexBranch(t *testing.T) {
+	url, first := initTestRepo(t)
+	repoDir := strings.TrimPrefix(url, "file://")
+	if out, err := exec.Command("git", "-C", repoDir, "branch", "deadbeef", first).CombinedOutput(); err != nil {
+		t.Fatalf("git branch failed: %v\n%s", err, out)
+	}
+
+	// 形如短 SHA 的分支名按分支获取
+	for _, cached := range []bool{true, false} {
+		cfg := &Config{RepoPath: t.TempDir(), Cache: CacheConfig{Disabled: !cached}}
+		src, err := FetchSource(cfg, &ParsedSource{Type: "git", URL: url, Ref: "deadbeef"})
+		if err != nil {
+			t.Fatalf("cached=%v: FetchSource(deadbeef) failed: %v", cached, err)
+		}
+		if src.Commit != first {
+			t.Errorf("cached=%v: expected branch deadbeef at %s, got %s", cached, first, src.Commit)
+		}
+		src.Cleanup()
+	}
+}
+
+func TestInstallSkillRejectsUnsafeName(t *testing.T) {
+	source := t.TempDir()
+	os.WriteFile(filepath.Join(source, "SKILL.md"), []byte("---\nname: ../../x\n---\n"), 0644)
+	skill := parseSkillFile(source)
+	if skill == nil || skill.Name != "../../x" {
+		t.Fatalf("expected frontmatter name to be parsed, got %+v", skill)
+	}
+
+	base := t.TempDir()
+	cfg := &Config{RepoPath: filepath.Join(base, "pool", "repo")}
+	if err := InstallSkill(skill, cfg); err == nil {
+		t.Error("expected InstallSkill to reject a name outside the pool")
+	}
+	if err := UpdateSkill(skill, cfg); err == nil {
+		t.Error("expected UpdateSkill to reject a name outside the pool")
+	}
+	if _, err := os.Stat(filepath.Join(base, "x")); !os.IsNotExist(err) {
+		t.Error("nothing should be written outside the pool")
+	}
+}
+
+func TestDiscoverSkillsReportsUnreadableDir(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(file, []byte("x"), 0644)
+	if _, err := DiscoverSkills(file, ""); err == nil {
+		t.Error("expected an error for a path that cannot be read as a directory")
+	}
+	if _, err := DiscoverSkills(t.TempDir(), "missing"); err == nil {
+		t.Error("expected an error for a missing subpath")
+	}
+}
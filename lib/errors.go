@@ -1,6 +1,9 @@
 package lib
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 // 自定义错误类型
 
@@ -42,6 +45,39 @@ func (e *SymlinkError) Error() string {
 	return fmt.Sprintf("symlink %s failed for %s: %s", e.Op, e.Path, e.Reason)
 }
 
+// OfflineError 离线模式下无法解析的源
+type OfflineError struct {
+	Source string
+}
+
+func (e *OfflineError) Error() string {
+	return fmt.Sprintf("cannot resolve %s offline: not in the git cache and no vendored archive", e.Source)
+}
+
+// AuthError 访问远程源时认证失败
+type AuthError struct {
+	URL      string // 已脱敏的地址
+	Host     string
+	SSH      bool
+	Reason   string
+	Origin   string // 被拒绝的凭据来源，未提供凭据时为空
+	Withheld string // 因地址为 http:// 而未发送的令牌来源
+}
+
+func (e *AuthError) Error() string {
+	msg := fmt.Sprintf("authentication failed for %s: %s", e.URL, e.Reason)
+	switch {
+	case e.Origin != "":
+		return fmt.Sprintf("%s (credentials from %s were rejected)", msg, e.Origin)
+	case e.Withheld != "":
+		return fmt.Sprintf("%s (the token from %s is not sent over http://; set allow_http = true for %s in %s)",
+			msg, e.Withheld, e.Host, CredentialsFileName)
+	case e.SSH:
+		return fmt.Sprintf("%s (check your SSH agent or set ssh_key for %s in %s)", msg, e.Host, CredentialsFileName)
+	}
+	return fmt.Sprintf("%s (set %s or add %s to %s)", msg, TokenEnvName(e.Host), e.Host, CredentialsFileName)
+}
+
 // IsModuleNotFound 检查是否为模块未找到错误
 func IsModuleNotFound(err error) bool {
 	_, ok := err.(*ModuleNotFoundError)
@@ -53,3 +89,15 @@ func IsPlatformNotFound(err error) bool {
 	_, ok := err.(*PlatformNotFoundError)
 	return ok
 }
+
+// IsOffline 检查是否为离线无法解析错误
+func IsOffline(err error) bool {
+	_, ok := err.(*OfflineError)
+	return ok
+}
+
+// IsAuthError 检查是否为认证失败错误（包括被包装的错误）
+func IsAuthError(err error) bool {
+	var authErr *AuthError
+	return errors.As(err, &authErr)
+}
@@ -60,3 +60,16 @@ func TestSymlinkError(t *testing.T) {
 		t.Errorf("expected '%s', got '%s'", expected, err.Error())
 	}
 }
+
+func TestOfflineError(t *testing.T) {
+	err := &OfflineError{Source: "https://github.com/o/r.git@main"}
+
+	expected := "cannot resolve https://github.com/o/r.git@main offline: not in the git cache and no vendored archive"
+	if err.Error() != expected {
+		t.Errorf("expected '%s', got '%s'", expected, err.Error())
+	}
+
+	if !IsOffline(err) {
+		t.Error("IsOffline should return true")
+	}
+}
@@ -0,0 +1,152 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SkillManifest SKILL.md / AGENT.md 的 YAML frontmatter
+type SkillManifest struct {
+	Name         string            `yaml:"name" json:"name,omitempty"`
+	Description  string            `yaml:"description" json:"description,omitempty"`
+	Version      string            `yaml:"version" json:"version,omitempty"`
+	License      string            `yaml:"license" json:"license,omitempty"`
+	AllowedTools StringList        `yaml:"allowed-tools" json:"allowed_tools,omitempty"`
+	Tags         StringList        `yaml:"tags" json:"tags,omitempty"`
+	Metadata     map[string]string `yaml:"metadata" json:"metadata,omitempty"`
+
+	HasFrontmatter bool   `yaml:"-" json:"-"` // 文件以 --- 开头的 frontmatter
+	Body           string `yaml:"-" json:"-"` // frontmatter 之后的正文
+}
+
+// StringList 字符串列表，YAML 中可写为序列，或逗号/空格分隔的字符串（括号内的空格不分隔，如 Bash(git add:*)）
+type StringList []string
+
+// UnmarshalYAML 解析序列或分隔字符串
+func (l *StringList) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*l = splitList(node.Value)
+		return nil
+	case yaml.SequenceNode:
+		var items []string
+		if err := node.Decode(&items); err != nil {
+			return err
+		}
+		*l = items
+		return nil
+	}
+	return fmt.Errorf("line %d: expected a list or a comma-separated string", node.Line)
+}
+
+// splitList 按逗号分隔；没有逗号时按空格分隔，括号内的内容保持完整
+func splitList(s string) []string {
+	seps := " \t\n"
+	if strings.Contains(s, ",") {
+		seps = ","
+	}
+
+	var items []string
+	depth := 0
+	start := 0
+	flush := func(end int) {
+		if item := strings.TrimSpace(s[start:end]); item != "" {
+			items = append(items, item)
+		}
+	}
+	for i, r := range s {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case depth == 0 && strings.ContainsRune(seps, r):
+			flush(i)
+			start = i + 1
+		}
+	}
+	flush(len(s))
+	return items
+}
+
+// SkillFileName 返回类别对应的描述文件名
+func SkillFileName(category string) string {
+	if category == "agent" {
+		return "AGENT.md"
+	}
+	return "SKILL.md"
+}
+
+// LoadSkillManifest 读取模块目录中 SKILL.md / AGENT.md 的 frontmatter
+func LoadSkillManifest(dir, category string) (*SkillManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, SkillFileName(category)))
+	if err != nil {
+		return nil, err
+	}
+	return ParseSkillManifest(data)
+}
+
+// ParseSkillManifest 解析 Markdown 内容开头的 YAML frontmatter
+// 没有 frontmatter 时返回空清单；frontmatter 未指定 description 时使用正文第一行非标题内容。
+// YAML 有误时仍返回带正文的清单和错误
+func ParseSkillManifest(content []byte) (*SkillManifest, error) {
+	m := &SkillManifest{}
+	front, body, ok := splitFrontmatter(content)
+	m.Body = string(body)
+
+	var err error
+	if ok {
+		m.HasFrontmatter = true
+		if err = yaml.Unmarshal(front, m); err != nil {
+			err = fmt.Errorf("invalid frontmatter: %w", err)
+		}
+	}
+	m.Name = strings.TrimSpace(m.Name)
+	m.Description = strings.TrimSpace(m.Description)
+	if m.Description == "" {
+		m.Description = firstParagraphLine(m.Body)
+	}
+	return m, err
+}
+
+// splitFrontmatter 拆分 --- 包围的 frontmatter 和正文
+func splitFrontmatter(content []byte) ([]byte, []byte, bool) {
+	content = bytes.TrimPrefix(content, []byte("\ufeff"))
+	content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+
+	first, rest, found := bytes.Cut(content, []byte("\n"))
+	if !found || string(bytes.TrimSpace(first)) != "---" {
+		return nil, content, false
+	}
+
+	offset := 0
+	for offset <= len(rest) {
+		line, next, more := bytes.Cut(rest[offset:], []byte("\n"))
+		if trimmed := string(bytes.TrimRight(line, " \t")); trimmed == "---" || trimmed == "..." {
+			return rest[:offset], next, true
+		}
+		if !more {
+			break
+		}
+		offset += len(line) + 1
+	}
+	// 没有结束标记，不视为 frontmatter
+	return nil, content, false
+}
+
+// firstParagraphLine 返回正文中第一行非空、非标题的内容
+func firstParagraphLine(body string) string {
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line == "---" {
+			continue
+		}
+		return line
+	}
+	return ""
+}
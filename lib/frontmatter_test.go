@@ -0,0 +1,125 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseSkillManifest(t *testing.T) {
+	m, err := ParseSkillManifest([]byte(`---
+name: "code-review"
+description: >
+  Reviews code changes
+  for common mistakes.
+version: 1.0
+license: MIT
+allowed-tools: Read, Grep, Bash(git diff:*)
+tags: [review, git]
+metadata:
+  author: acme
+---
+# Code Review
+
+Body text.
+`))
+	if err != nil {
+		t.Fatalf("ParseSkillManifest failed: %v", err)
+	}
+	if m.Name != "code-review" || m.Version != "1.0" || m.License != "MIT" {
+		t.Errorf("unexpected manifest: %+v", m)
+	}
+	if m.Description != "Reviews code changes for common mistakes." {
+		t.Errorf("unexpected description: %q", m.Description)
+	}
+	if want := (StringList{"Read", "Grep", "Bash(git diff:*)"}); !reflect.DeepEqual(m.AllowedTools, want) {
+		t.Errorf("allowed-tools = %q, want %q", m.AllowedTools, want)
+	}
+	if want := (StringList{"review", "git"}); !reflect.DeepEqual(m.Tags, want) {
+		t.Errorf("tags = %q, want %q", m.Tags, want)
+	}
+	if m.Metadata["author"] != "acme" || !m.HasFrontmatter {
+		t.Errorf("unexpected metadata: %+v", m)
+	}
+	if m.Body != "# Code Review\n\nBody text.\n" {
+		t.Errorf("unexpected body: %q", m.Body)
+	}
+}
+
+func TestParseSkillManifestLiteralDescription(t *testing.T) {
+	m, err := ParseSkillManifest([]byte("---\r\nname: notes\r\ndescription: |\r\n  Line one\r\n  Line two\r\n---\r\nBody\r\n"))
+	if err != nil {
+		t.Fatalf("ParseSkillManifest failed: %v", err)
+	}
+	if m.Name != "notes" || m.Description != "Line one\nLine two" {
+		t.Errorf("unexpected manifest: %+v", m)
+	}
+}
+
+func TestStringListForms(t *testing.T) {
+	tests := []struct {
+		yaml string
+		want StringList
+	}{
+		{"allowed-tools: Read Grep Bash(git add:*)", StringList{"Read", "Grep", "Bash(git add:*)"}},
+		{"allowed-tools: Read,Write", StringList{"Read", "Write"}},
+		{"allowed-tools:\n  - Read\n  - Bash(npm test)", StringList{"Read", "Bash(npm test)"}},
+		{"allowed-tools: ''", nil},
+	}
+	for _, tt := range tests {
+		m, err := ParseSkillManifest([]byte("---\n" + tt.yaml + "\n---\n"))
+		if err != nil {
+			t.Errorf("%q: %v", tt.yaml, err)
+			continue
+		}
+		if !reflect.DeepEqual(m.AllowedTools, tt.want) {
+			t.Errorf("%q: got %q, want %q", tt.yaml, m.AllowedTools, tt.want)
+		}
+	}
+}
+
+func TestParseSkillManifestWithoutFrontmatter(t *testing.T) {
+	m, err := ParseSkillManifest([]byte("# Title\n\nFirst paragraph.\n"))
+	if err != nil {
+		t.Fatalf("ParseSkillManifest failed: %v", err)
+	}
+	if m.HasFrontmatter || m.Name != "" || m.Description != "First paragraph." {
+		t.Errorf("unexpected manifest: %+v", m)
+	}
+
+	// 没有结束标记时整个文件都是正文
+	m, _ = ParseSkillManifest([]byte("---\nname: x\n"))
+	if m.HasFrontmatter || m.Name != "" {
+		t.Errorf("unterminated frontmatter should be ignored: %+v", m)
+	}
+}
+
+func TestParseSkillManifestInvalid(t *testing.T) {
+	m, err := ParseSkillManifest([]byte("---\nname: [unclosed\n---\nFallback line\n"))
+	if err == nil {
+		t.Fatal("expected error for invalid YAML")
+	}
+	if m == nil || !m.HasFrontmatter || m.Description != "Fallback line" {
+		t.Errorf("expected manifest with body fallback, got %+v", m)
+	}
+}
+
+func TestLoadModuleManifest(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "skill", "demo")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte("---\nname: demo\ndescription: >-\n  Folded\n  text\nversion: 2.1.0\n---\n"), 0644)
+
+	mod, err := loadModule("demo", "skill", dir)
+	if err != nil {
+		t.Fatalf("loadModule failed: %v", err)
+	}
+	if mod.Description != "Folded text" || mod.Manifest == nil || mod.Manifest.Version != "2.1.0" {
+		t.Errorf("unexpected module: %+v", mod)
+	}
+
+	skill := parseSkillFile(dir)
+	if skill == nil || skill.Name != "demo" || skill.Description != "Folded text" || skill.Manifest.Version != "2.1.0" {
+		t.Errorf("unexpected discovered skill: %+v", skill)
+	}
+}
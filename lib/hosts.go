@@ -0,0 +1,206 @@
+package lib
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// 代码托管平台类型，决定网页地址（tree/blob/src）的解析方式
+const (
+	HostGitHub    = "github"    // github.com 与 GitHub Enterprise
+	HostGitLab    = "gitlab"    // gitlab.com 与自建 GitLab，支持子群组
+	HostGitea     = "gitea"     // Gitea / Forgejo（如 codeberg.org）
+	HostBitbucket = "bitbucket" // bitbucket.org 与 Bitbucket Server/Data Center
+)
+
+// hostTypes 支持的平台类型
+var hostTypes = []string{HostGitHub, HostGitLab, HostGitea, HostBitbucket}
+
+// builtinHosts 内置的公共托管平台
+var builtinHosts = map[string]string{
+	"github.com":    HostGitHub,
+	"gitlab.com":    HostGitLab,
+	"bitbucket.org": HostBitbucket,
+	"codeberg.org":  HostGitea,
+	"gitea.com":     HostGitea,
+}
+
+// HostConfig 自建托管平台配置（platforms.toml 中的 [[hosts]]）
+type HostConfig struct {
+	Host string `toml:"host"` // 主机名，可带端口，如 git.example.com:8443
+	Type string `toml:"type"` // github, gitlab, gitea, bitbucket
+}
+
+// HostRegistry 托管平台注册表：主机名 -> 平台类型
+// nil 注册表只包含内置平台
+type HostRegistry struct {
+	hosts map[string]string
+}
+
+// NewHostRegistry 创建包含内置平台和 entries 的注册表，entries 可以覆盖内置平台
+func NewHostRegistry(entries []HostConfig) (*HostRegistry, error) {
+	r := &HostRegistry{hosts: make(map[string]string, len(builtinHosts)+len(entries))}
+	for host, typ := range builtinHosts {
+		r.hosts[host] = typ
+	}
+	for _, e := range entries {
+		host := strings.ToLower(strings.TrimSpace(e.Host))
+		if host == "" || strings.ContainsAny(host, "/@ ") || strings.Contains(host, "://") {
+			return nil, fmt.Errorf("hosts: invalid host %q (expected a host name such as git.example.com)", e.Host)
+		}
+		if !isHostType(e.Type) {
+			return nil, fmt.Errorf("hosts: %s has unknown type %q (expected one of %s)", e.Host, e.Type, strings.Join(hostTypes, ", "))
+		}
+		r.hosts[host] = e.Type
+	}
+	return r, nil
+}
+
+func isHostType(typ string) bool {
+	for _, t := range hostTypes {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// Lookup 返回主机的平台类型
+func (r *HostRegistry) Lookup(host string) (string, bool) {
+	host = strings.ToLower(host)
+	if r == nil {
+		typ, ok := builtinHosts[host]
+		return typ, ok
+	}
+	typ, ok := r.hosts[host]
+	return typ, ok
+}
+
+// parseHostURL 按注册的托管平台解析网页或克隆地址，主机未注册或地址形式不认识时返回 nil
+// 输入可以省略 scheme（如 git.example.com/owner/repo），此时使用 https
+func parseHostURL(input string, hosts *HostRegistry) *ParsedSource {
+	raw := input
+	if !strings.Contains(raw, "://") {
+		first, _, _ := strings.Cut(raw, "/")
+		if !strings.Contains(first, ".") {
+			return nil
+		}
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.User != nil {
+		return nil
+	}
+	typ, ok := hosts.Lookup(u.Host)
+	if !ok {
+		return nil
+	}
+
+	var segs []string
+	for _, s := range strings.Split(u.Path, "/") {
+		if s != "" {
+			segs = append(segs, s)
+		}
+	}
+
+	var repo, ref, subpath string
+	blob := false
+	switch typ {
+	case HostGitHub:
+		// /owner/repo[/tree|blob/<ref>[/path]]
+		if len(segs) < 2 || len(segs) == 3 {
+			return nil
+		}
+		repo = segs[0] + "/" + segs[1]
+		if len(segs) > 2 {
+			if segs[2] != "tree" && segs[2] != "blob" {
+				return nil
+			}
+			blob = segs[2] == "blob"
+			ref, subpath = segs[3], strings.Join(segs[4:], "/")
+		}
+
+	case HostGitLab:
+		// /group/sub/.../repo[/-/tree|blob/<ref>[/path]]
+		project := segs
+		for i, s := range segs {
+			if s == "-" {
+				project = segs[:i]
+				rest := segs[i+1:]
+				if len(rest) < 2 || (rest[0] != "tree" && rest[0] != "blob") {
+					return nil
+				}
+				blob = rest[0] == "blob"
+				ref, subpath = rest[1], strings.Join(rest[2:], "/")
+				break
+			}
+		}
+		if len(project) < 2 {
+			return nil
+		}
+		repo = strings.Join(project, "/")
+
+	case HostGitea:
+		// /owner/repo[/src/branch|tag|commit/<ref>[/path]]
+		if len(segs) < 2 {
+			return nil
+		}
+		repo = segs[0] + "/" + segs[1]
+		if len(segs) > 2 {
+			if len(segs) < 5 || segs[2] != "src" || (segs[3] != "branch" && segs[3] != "tag" && segs[3] != "commit") {
+				return nil
+			}
+			ref, subpath = segs[4], strings.Join(segs[5:], "/")
+		}
+
+	case HostBitbucket:
+		// Bitbucket Server: /projects/KEY/repos/repo[/browse[/path]]?at=refs/heads/<ref>
+		if len(segs) >= 4 && segs[0] == "projects" && segs[2] == "repos" {
+			if len(segs) > 4 && segs[4] != "browse" {
+				return nil
+			}
+			if len(segs) > 5 {
+				subpath = strings.Join(segs[5:], "/")
+			}
+			ref = strings.TrimPrefix(strings.TrimPrefix(u.Query().Get("at"), "refs/heads/"), "refs/tags/")
+			return &ParsedSource{
+				Type:    typ,
+				URL:     fmt.Sprintf("%s://%s/scm/%s/%s.git", u.Scheme, u.Host, strings.ToLower(segs[1]), strings.TrimSuffix(segs[3], ".git")),
+				Ref:     ref,
+				Subpath: trimSkillFile(subpath, false),
+			}
+		}
+		// bitbucket.org: /workspace/repo[/src/<ref>[/path]]
+		if len(segs) < 2 {
+			return nil
+		}
+		repo = segs[0] + "/" + segs[1]
+		if len(segs) > 2 {
+			if len(segs) < 4 || segs[2] != "src" {
+				return nil
+			}
+			ref, subpath = segs[3], strings.Join(segs[4:], "/")
+		}
+	}
+
+	return &ParsedSource{
+		Type:    typ,
+		URL:     fmt.Sprintf("%s://%s/%s.git", u.Scheme, u.Host, strings.TrimSuffix(repo, ".git")),
+		Ref:     ref,
+		Subpath: trimSkillFile(subpath, blob),
+	}
+}
+
+// trimSkillFile 指向文件的地址（blob 或 SKILL.md/AGENT.md）取其所在目录
+func trimSkillFile(subpath string, blob bool) string {
+	base := path.Base(subpath)
+	if blob || base == SkillFileName("skill") || base == SkillFileName("agent") {
+		subpath = path.Dir(subpath)
+	}
+	if subpath == "." {
+		return ""
+	}
+	return subpath
+}
@@ -0,0 +1,80 @@
+package lib
+
+import "testing"
+
+func TestParseSourceWithHosts(t *testing.T) {
+	hosts, err := NewHostRegistry([]HostConfig{
+		{Host: "ghe.corp.example", Type: "github"},
+		{Host: "git.example.com", Type: "gitlab"},
+		{Host: "gitea.example.com:3000", Type: "gitea"},
+		{Host: "bitbucket.example.com", Type: "bitbucket"},
+	})
+	if err != nil {
+		t.Fatalf("NewHostRegistry failed: %v", err)
+	}
+
+	tests := []struct {
+		input   string
+		typ     string
+		url     string
+		ref     string
+		subpath string
+	}{
+		// 内置平台
+		{"https://github.com/owner/repo", "github", "https://github.com/owner/repo.git", "", ""},
+		{"https://github.com/owner/repo.git", "github", "https://github.com/owner/repo.git", "", ""},
+		{"https://github.com/owner/repo/tree/main/skills/x", "github", "https://github.com/owner/repo.git", "main", "skills/x"},
+		{"https://github.com/owner/repo/blob/v1/skills/x/SKILL.md", "github", "https://github.com/owner/repo.git", "v1", "skills/x"},
+		{"github.com/owner/repo/tree/main", "github", "https://github.com/owner/repo.git", "main", ""},
+		{"https://gitlab.com/group/sub/repo/-/tree/main/skills/x", "gitlab", "https://gitlab.com/group/sub/repo.git", "main", "skills/x"},
+		{"https://gitlab.com/group/sub/repo", "gitlab", "https://gitlab.com/group/sub/repo.git", "", ""},
+		{"https://bitbucket.org/ws/repo/src/main/skills/x/", "bitbucket", "https://bitbucket.org/ws/repo.git", "main", "skills/x"},
+		{"https://codeberg.org/owner/repo/src/branch/main/skills/x", "gitea", "https://codeberg.org/owner/repo.git", "main", "skills/x"},
+
+		// 自建平台
+		{"https://ghe.corp.example/team/repo/tree/release/skills/x", "github", "https://ghe.corp.example/team/repo.git", "release", "skills/x"},
+		{"https://git.example.com/a/b/c/repo/-/blob/main/x/AGENT.md", "gitlab", "https://git.example.com/a/b/c/repo.git", "main", "x"},
+		{"https://git.example.com/a/b/c/repo.git", "gitlab", "https://git.example.com/a/b/c/repo.git", "", ""},
+		{"http://gitea.example.com:3000/owner/repo/src/tag/v2/skills/x/SKILL.md", "gitea", "http://gitea.example.com:3000/owner/repo.git", "v2", "skills/x"},
+		{"https://bitbucket.example.com/projects/TEAM/repos/skills/browse/pdf?at=refs/heads/main", "bitbucket", "https://bitbucket.example.com/scm/team/skills.git", "main", "pdf"},
+		{"https://GHE.corp.example/team/repo@3f1c2a9", "github", "https://GHE.corp.example/team/repo.git", "3f1c2a9", ""},
+
+		// 未注册的主机和不认识的地址形式
+		{"https://unknown.example.com/owner/repo/tree/main/x", "git", "https://unknown.example.com/owner/repo/tree/main/x", "", ""},
+		{"https://github.com/owner/repo/issues/1", "git", "https://github.com/owner/repo/issues/1", "", ""},
+		{"git@ghe.corp.example:team/repo.git", "git", "git@ghe.corp.example:team/repo.git", "", ""},
+	}
+	for _, tt := range tests {
+		parsed := ParseSourceWith(tt.input, hosts)
+		if parsed.Type != tt.typ || parsed.URL != tt.url || parsed.Ref != tt.ref || parsed.Subpath != tt.subpath {
+			t.Errorf("ParseSourceWith(%s) = {%s %s %s %s}, expected {%s %s %s %s}",
+				tt.input, parsed.Type, parsed.URL, parsed.Ref, parsed.Subpath, tt.typ, tt.url, tt.ref, tt.subpath)
+		}
+	}
+
+	// 不传注册表时只认识内置平台
+	if parsed := ParseSource("https://ghe.corp.example/team/repo/tree/main/x"); parsed.Type != "git" {
+		t.Errorf("expected unregistered host to be a direct git URL, got %+v", parsed)
+	}
+}
+
+func TestNewHostRegistryValidation(t *testing.T) {
+	for _, entry := range []HostConfig{
+		{Host: "", Type: "gitlab"},
+		{Host: "https://git.example.com", Type: "gitlab"},
+		{Host: "git.example.com/path", Type: "gitlab"},
+		{Host: "git.example.com", Type: "svn"},
+	} {
+		if _, err := NewHostRegistry([]HostConfig{entry}); err == nil {
+			t.Errorf("expected error for %+v", entry)
+		}
+	}
+
+	hosts, err := NewHostRegistry([]HostConfig{{Host: "GitHub.com", Type: "gitea"}})
+	if err != nil {
+		t.Fatalf("NewHostRegistry failed: %v", err)
+	}
+	if typ, _ := hosts.Lookup("github.com"); typ != "gitea" {
+		t.Errorf("entries should override built-in hosts, got %s", typ)
+	}
+}
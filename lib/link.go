@@ -8,6 +8,16 @@ import (
 
 // CreateSymlink 创建软链接
 func CreateSymlink(source, target string, isProject bool) error {
+	return createSymlink(source, target, false)
+}
+
+// CreateRelativeSymlink 创建以相对路径指向源的软链接
+// 链接目录和仓库一起移动或挂载时（如提交到项目、挂载到容器）链接仍然有效
+func CreateRelativeSymlink(source, target string) error {
+	return createSymlink(source, target, true)
+}
+
+func createSymlink(source, target string, relative bool) error {
 	// 展开路径
 	source = ResolvePath(source)
 	target = ResolvePath(target)
@@ -41,7 +51,28 @@ func CreateSymlink(source, target string, isProject bool) error {
 	}
 
 	// 创建软链接
-	return os.Symlink(source, target)
+	dest := source
+	if relative {
+		rel, err := filepath.Rel(targetDir, source)
+		if err != nil {
+			return fmt.Errorf("cannot make %s relative to %s: %v", source, targetDir, err)
+		}
+		dest = rel
+	}
+	if err := os.Symlink(dest, target); err != nil {
+		return err
+	}
+
+	// 目标目录的上级是软链接时，相对路径按实际位置解析，可能指向别处
+	if relative {
+		srcInfo, err1 := os.Stat(source)
+		dstInfo, err2 := os.Stat(target)
+		if err1 != nil || err2 != nil || !os.SameFile(srcInfo, dstInfo) {
+			os.Remove(target)
+			return fmt.Errorf("relative link %s does not resolve to %s (a parent directory is a symlink)", target, source)
+		}
+	}
+	return nil
 }
 
 // RemoveSymlink 移除软链接
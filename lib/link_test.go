@@ -75,6 +75,49 @@ func TestCreateAndRemoveSymlink(t *testing.T) {
 	}
 }
 
+func TestCreateRelativeSymlink(t *testing.T) {
+	tmpDir := t.TempDir()
+	sourceDir := filepath.Join(tmpDir, "repo", "skill", "foo")
+	targetLink := filepath.Join(tmpDir, "project", ".claude", "skills", "foo")
+	os.MkdirAll(sourceDir, 0755)
+
+	if err := CreateRelativeSymlink(sourceDir, targetLink); err != nil {
+		t.Fatalf("CreateRelativeSymlink failed: %v", err)
+	}
+	dest, _ := ReadSymlink(targetLink)
+	if expected := filepath.Join("..", "..", "..", "repo", "skill", "foo"); dest != expected {
+		t.Errorf("symlink points to %s, expected %s", dest, expected)
+	}
+
+	// 整体移动后仍然有效
+	moved := filepath.Join(t.TempDir(), "moved")
+	if err := os.Rename(tmpDir, moved); err != nil {
+		t.Skipf("cannot move temp dir: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(moved, "project", ".claude", "skills", "foo")); err != nil {
+		t.Errorf("relative link broke after moving: %v", err)
+	}
+	os.Rename(moved, tmpDir)
+}
+
+func TestCreateRelativeSymlinkThroughSymlinkedParent(t *testing.T) {
+	tmpDir := t.TempDir()
+	sourceDir := filepath.Join(tmpDir, "repo", "skill", "foo")
+	os.MkdirAll(sourceDir, 0755)
+	os.MkdirAll(filepath.Join(tmpDir, "elsewhere", "a", "deep", "skills"), 0755)
+	os.MkdirAll(filepath.Join(tmpDir, "project"), 0755)
+	// project/.claude → elsewhere/a/deep，相对路径按实际位置解析会指向别处
+	os.Symlink(filepath.Join(tmpDir, "elsewhere", "a", "deep"), filepath.Join(tmpDir, "project", ".claude"))
+
+	target := filepath.Join(tmpDir, "project", ".claude", "skills", "foo")
+	if err := CreateRelativeSymlink(sourceDir, target); err == nil {
+		t.Error("expected error for relative link through a symlinked parent")
+	}
+	if IsSymlink(target) {
+		t.Error("unresolvable relative link should have been removed")
+	}
+}
+
 func TestCreateSymlinkProtectsRealFiles(t *testing.T) {
 	tmpDir := t.TempDir()
 	sourceDir := filepath.Join(tmpDir, "source")
@@ -0,0 +1,264 @@
+package lib
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Agent Skills 规范 (agentskills.io) 的限制
+const (
+	MaxNameLength          = 64
+	MaxDescriptionLength   = 1024
+	MaxCompatibilityLength = 500
+	MaxSkillLines          = 500     // SKILL.md 建议的最大行数，更长的内容应拆到引用文件中
+	MaxBundledFileSize     = 1 << 20 // 单个附带文件的建议上限
+)
+
+// 检查结果级别
+const (
+	LintError   = "error"   // 不符合规范，sk lint 以非零状态退出
+	LintWarning = "warning" // 建议修改
+)
+
+// 检查规则
+const (
+	RuleMissingFile         = "missing-file"
+	RuleFrontmatter         = "frontmatter"
+	RuleNameRequired        = "name-required"
+	RuleNameFormat          = "name-format"
+	RuleNameLength          = "name-length"
+	RuleNameMismatch        = "name-mismatch"
+	RuleDescriptionRequired = "description-required"
+	RuleDescriptionLength   = "description-length"
+	RuleCompatibilityLength = "compatibility-length"
+	RuleUnsupportedField    = "unsupported-field"
+	RuleBrokenLink          = "broken-link"
+	RuleFileSize            = "file-size"
+)
+
+// skillFields SKILL.md frontmatter 中支持的字段：规范字段，加上 Skill Kit 读取的 version 和 tags
+var skillFields = map[string]bool{
+	"name":          true,
+	"description":   true,
+	"license":       true,
+	"compatibility": true,
+	"allowed-tools": true,
+	"metadata":      true,
+	"version":       true,
+	"tags":          true,
+}
+
+// skillNamePattern 小写字母、数字和单个连字符，不能以连字符开头或结尾
+var skillNamePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// markdownLinkPattern Markdown 链接和图片的目标
+var markdownLinkPattern = regexp.MustCompile(`!?\[[^\]]*\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+
+// LintIssue 单个检查问题
+type LintIssue struct {
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	File     string `json:"file,omitempty"` // 相对模块目录的路径
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+}
+
+// LintResult 单个模块的检查结果
+type LintResult struct {
+	Name     string      `json:"name"`
+	Category string      `json:"category"`
+	Path     string      `json:"path"`
+	Issues   []LintIssue `json:"issues"`
+}
+
+// LintReport sk lint 的输出
+type LintReport struct {
+	SchemaVersion int          `json:"schema_version"`
+	Errors        int          `json:"errors"`
+	Warnings      int          `json:"warnings"`
+	Modules       []LintResult `json:"modules"`
+}
+
+// Add 加入一个模块的检查结果
+func (r *LintReport) Add(result LintResult) {
+	for _, issue := range result.Issues {
+		if issue.Severity == LintError {
+			r.Errors++
+		} else {
+			r.Warnings++
+		}
+	}
+	r.Modules = append(r.Modules, result)
+}
+
+// NewLintReport 创建空的检查报告
+func NewLintReport() LintReport {
+	return LintReport{SchemaVersion: ReportSchemaVersion, Modules: []LintResult{}}
+}
+
+// LintModule 检查模块目录是否符合 Agent Skills 规范
+// 目录中没有 SKILL.md 时按 agent 检查 AGENT.md；agent 不检查不支持的字段
+func LintModule(dir string) LintResult {
+	category := "skill"
+	if _, err := os.Stat(filepath.Join(dir, "SKILL.md")); err != nil {
+		if _, err := os.Stat(filepath.Join(dir, "AGENT.md")); err == nil {
+			category = "agent"
+		}
+	}
+	fileName := SkillFileName(category)
+	result := LintResult{Name: filepath.Base(dir), Category: category, Path: dir, Issues: []LintIssue{}}
+	add := func(severity, rule, file string, line int, format string, args ...any) {
+		result.Issues = append(result.Issues, LintIssue{
+			Severity: severity,
+			Rule:     rule,
+			File:     file,
+			Line:     line,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, fileName))
+	if err != nil {
+		add(LintError, RuleMissingFile, "", 0, "%s not found", fileName)
+		return result
+	}
+
+	m, err := ParseSkillManifest(content)
+	switch {
+	case !m.HasFrontmatter:
+		add(LintError, RuleFrontmatter, fileName, 1, "missing YAML frontmatter (--- ... ---)")
+	case err != nil:
+		add(LintError, RuleFrontmatter, fileName, 1, "%v", err)
+	}
+
+	front, _, _ := splitFrontmatter(content)
+	var fields map[string]any
+	yaml.Unmarshal(front, &fields)
+
+	if m.HasFrontmatter && err == nil {
+		lintName(m.Name, filepath.Base(dir), fileName, add)
+
+		if desc, _ := fields["description"].(string); strings.TrimSpace(desc) == "" {
+			add(LintError, RuleDescriptionRequired, fileName, 0, "description is required")
+		} else if n := utf8.RuneCountInString(m.Description); n > MaxDescriptionLength {
+			add(LintError, RuleDescriptionLength, fileName, 0, "description is %d characters (max %d)", n, MaxDescriptionLength)
+		}
+
+		if compat, ok := fields["compatibility"].(string); ok && utf8.RuneCountInString(compat) > MaxCompatibilityLength {
+			add(LintError, RuleCompatibilityLength, fileName, 0, "compatibility is %d characters (max %d)", utf8.RuneCountInString(compat), MaxCompatibilityLength)
+		}
+
+		if category == "skill" {
+			var unknown []string
+			for key := range fields {
+				if !skillFields[key] {
+					unknown = append(unknown, key)
+				}
+			}
+			sort.Strings(unknown)
+			for _, key := range unknown {
+				add(LintWarning, RuleUnsupportedField, fileName, 0, "unsupported field %q (move it under metadata)", key)
+			}
+		}
+	}
+
+	// frontmatter 占用的行数，用于给出正文中链接的行号
+	offset := strings.Count(string(content), "\n") - strings.Count(m.Body, "\n")
+	for _, link := range brokenLinks(dir, m.Body) {
+		add(LintError, RuleBrokenLink, fileName, link.line+offset, "%s", link.message)
+	}
+
+	if lines := strings.Count(m.Body, "\n"); category == "skill" && lines > MaxSkillLines {
+		add(LintWarning, RuleFileSize, fileName, 0, "body is %d lines (recommended max %d); move details into referenced files", lines, MaxSkillLines)
+	}
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.Size() > MaxBundledFileSize {
+			rel, _ := filepath.Rel(dir, path)
+			add(LintWarning, RuleFileSize, filepath.ToSlash(rel), 0, "file is %s (recommended max %s)", FormatSize(info.Size()), FormatSize(MaxBundledFileSize))
+		}
+		return nil
+	})
+
+	return result
+}
+
+// lintName 检查 name 字段
+func lintName(name, dirName, fileName string, add func(severity, rule, file string, line int, format string, args ...any)) {
+	if name == "" {
+		add(LintError, RuleNameRequired, fileName, 0, "name is required")
+		return
+	}
+	if n := utf8.RuneCountInString(name); n > MaxNameLength {
+		add(LintError, RuleNameLength, fileName, 0, "name is %d characters (max %d)", n, MaxNameLength)
+	}
+	if !skillNamePattern.MatchString(name) {
+		add(LintError, RuleNameFormat, fileName, 0, "name %q must use lowercase letters, digits and single hyphens, and must not start or end with a hyphen", name)
+	}
+	if name != dirName {
+		add(LintError, RuleNameMismatch, fileName, 0, "name %q does not match directory name %q", name, dirName)
+	}
+}
+
+// lintLink 正文中有问题的链接
+type lintLink struct {
+	line    int
+	message string
+}
+
+// brokenLinks 查找正文中指向模块内不存在文件或模块目录之外的相对链接（忽略代码块）
+func brokenLinks(dir, body string) []lintLink {
+	var links []lintLink
+	inFence := false
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if trimmed := strings.TrimSpace(text); strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		for _, match := range markdownLinkPattern.FindAllStringSubmatch(text, -1) {
+			target := match[1]
+			if u, err := url.Parse(target); err != nil || u.Scheme != "" || strings.HasPrefix(target, "#") || strings.HasPrefix(target, "/") {
+				continue
+			}
+			target, _, _ = strings.Cut(target, "#")
+			target, _, _ = strings.Cut(target, "?")
+			if unescaped, err := url.PathUnescape(target); err == nil {
+				target = unescaped
+			}
+
+			path := filepath.Join(dir, filepath.FromSlash(target))
+			if path != filepath.Clean(dir) && !isWithin(path, dir) {
+				links = append(links, lintLink{line, fmt.Sprintf("link %s points outside the module", match[1])})
+				continue
+			}
+			if _, err := os.Stat(path); err != nil {
+				links = append(links, lintLink{line, fmt.Sprintf("link %s: file not found", match[1])})
+			}
+		}
+	}
+	return links
+}
@@ -0,0 +1,126 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeLintSkill(t *testing.T, name, content string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), name)
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte(content), 0644)
+	return dir
+}
+
+func lintRules(result LintResult) []string {
+	var rules []string
+	for _, issue := range result.Issues {
+		rules = append(rules, issue.Severity+":"+issue.Rule)
+	}
+	return rules
+}
+
+func TestLintModuleValid(t *testing.T) {
+	dir := writeLintSkill(t, "pdf-tools", "---\nname: pdf-tools\ndescription: Extract text from PDFs.\nlicense: MIT\nmetadata:\n  author: acme\n---\nSee [forms](forms.md#fill).\n")
+	os.WriteFile(filepath.Join(dir, "forms.md"), []byte("# Forms\n"), 0644)
+
+	result := LintModule(dir)
+	if len(result.Issues) != 0 {
+		t.Errorf("expected no issues, got %+v", result.Issues)
+	}
+}
+
+func TestLintModuleName(t *testing.T) {
+	tests := []struct {
+		dir, name string
+		want      []string
+	}{
+		{"demo", "", []string{"error:name-required"}},
+		{"Demo", "Demo", []string{"error:name-format"}},
+		{"a--b", "a--b", []string{"error:name-format"}},
+		{"demo", "-demo", []string{"error:name-format", "error:name-mismatch"}},
+		{"demo", "other", []string{"error:name-mismatch"}},
+		{strings.Repeat("a", 65), strings.Repeat("a", 65), []string{"error:name-length"}},
+	}
+	for _, tt := range tests {
+		dir := writeLintSkill(t, tt.dir, "---\nname: '"+tt.name+"'\ndescription: Demo.\n---\n")
+		if got := lintRules(LintModule(dir)); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("name %q in %q: got %v, want %v", tt.name, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestLintModuleFrontmatter(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{"# Demo\n\nNo frontmatter.\n", []string{"error:frontmatter"}},
+		{"---\nname: [demo\n---\n", []string{"error:frontmatter"}},
+		{"---\nname: demo\n---\nBody line.\n", []string{"error:description-required"}},
+		{"---\nname: demo\ndescription: " + strings.Repeat("x", MaxDescriptionLength+1) + "\n---\n", []string{"error:description-length"}},
+		{"---\nname: demo\ndescription: Demo.\nmodel: opus\nauthor: me\n---\n", []string{"warning:unsupported-field", "warning:unsupported-field"}},
+	}
+	for _, tt := range tests {
+		dir := writeLintSkill(t, "demo", tt.content)
+		if got := lintRules(LintModule(dir)); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%q: got %v, want %v", tt.content, got, tt.want)
+		}
+	}
+
+	if got := lintRules(LintModule(t.TempDir())); strings.Join(got, ",") != "error:missing-file" {
+		t.Errorf("empty dir: got %v", got)
+	}
+}
+
+func TestLintModuleLinks(t *testing.T) {
+	dir := writeLintSkill(t, "demo", `---
+name: demo
+description: Demo.
+---
+# Demo
+
+[ok](scripts/run.sh) ![img](missing.png)
+[web](https://example.com) [anchor](#usage) [escape](../other/SKILL.md)
+
+`+"```"+`
+[ignored](not-here.md)
+`+"```"+`
+`)
+	os.MkdirAll(filepath.Join(dir, "scripts"), 0755)
+	os.WriteFile(filepath.Join(dir, "scripts", "run.sh"), []byte("#!/bin/sh\n"), 0755)
+
+	result := LintModule(dir)
+	if got := lintRules(result); strings.Join(got, ",") != "error:broken-link,error:broken-link" {
+		t.Fatalf("got %v", got)
+	}
+	if result.Issues[0].Line != 7 || !strings.Contains(result.Issues[0].Message, "missing.png") {
+		t.Errorf("unexpected issue: %+v", result.Issues[0])
+	}
+	if result.Issues[1].Line != 8 || !strings.Contains(result.Issues[1].Message, "outside") {
+		t.Errorf("unexpected issue: %+v", result.Issues[1])
+	}
+}
+
+func TestLintModuleSize(t *testing.T) {
+	dir := writeLintSkill(t, "demo", "---\nname: demo\ndescription: Demo.\n---\n"+strings.Repeat("line\n", MaxSkillLines+1))
+	os.WriteFile(filepath.Join(dir, "data.bin"), make([]byte, MaxBundledFileSize+1), 0644)
+
+	result := LintModule(dir)
+	if got := lintRules(result); strings.Join(got, ",") != "warning:file-size,warning:file-size" {
+		t.Fatalf("got %v", got)
+	}
+	if result.Issues[1].File != "data.bin" {
+		t.Errorf("unexpected issue: %+v", result.Issues[1])
+	}
+
+	report := NewLintReport()
+	report.Add(result)
+	report.Add(LintModule(t.TempDir()))
+	if report.Errors != 1 || report.Warnings != 2 || len(report.Modules) != 2 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
@@ -0,0 +1,235 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	toml "github.com/pelletier/go-toml/v2"
+)
+
+// LockfileName 锁文件名（位于仓库根目录）
+const LockfileName = "skillkit.lock"
+
+// LockfileVersion 锁文件格式版本
+const LockfileVersion = 1
+
+// Lockfile 锁文件：记录每个已安装模块的来源
+type Lockfile struct {
+	Version          int         `toml:"version"`
+	DefaultPlatforms []string    `toml:"default_platforms,omitempty"` // 恢复时分发的平台
+	Modules          []LockEntry `toml:"module"`
+}
+
+// LockEntry 单个模块的来源记录
+type LockEntry struct {
+	Name        string    `toml:"name"`              // 安装目录名
+	Category    string    `toml:"category"`          // skill 或 agent
+	Source      string    `toml:"source"`            // 用户输入的原始源地址
+	Type        string    `toml:"type"`              // github, gitlab, git, local, archive
+	URL         string    `toml:"url"`               // Git URL、归档地址或本地绝对路径
+	Ref         string    `toml:"ref,omitempty"`     // 分支/标签
+	Subpath     string    `toml:"subpath,omitempty"` // 模块在源中的相对路径
+	Commit      string    `toml:"commit,omitempty"`  // 安装时解析到的提交
+	SHA256      string    `toml:"sha256,omitempty"`  // 归档文件的 SHA-256（仅归档源）
+	Hash        string    `toml:"hash"`              // 模块目录内容哈希
+	InstalledAt time.Time `toml:"installed_at"`
+
+	Link *LinkConfig `toml:"link,omitempty"` // 记录的链接名配置
+}
+
+// LockfilePath 返回仓库锁文件路径
+func LockfilePath(cfg *Config) string {
+	return filepath.Join(cfg.RepoPath, LockfileName)
+}
+
+// LoadLockfile 读取锁文件，文件不存在时返回空锁文件
+func LoadLockfile(path string) (*Lockfile, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Lockfile{Version: LockfileVersion}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var lf Lockfile
+	if err := toml.Unmarshal(data, &lf); err != nil {
+		return nil, err
+	}
+	if lf.Version == 0 {
+		lf.Version = LockfileVersion
+	}
+	for _, entry := range lf.Modules {
+		if err := entry.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return &lf, nil
+}
+
+// validate 检查锁记录中会被拼接为路径的字段，防止外部锁文件写到仓库或源目录之外
+func (e *LockEntry) validate() error {
+	if err := ValidateDirName(e.Name); err != nil {
+		return err
+	}
+	if err := ValidateCategory(e.Category); err != nil {
+		return fmt.Errorf("%s: %w", e.Name, err)
+	}
+	if e.Subpath != "" {
+		sub := filepath.FromSlash(e.Subpath)
+		if filepath.IsAbs(sub) || strings.HasPrefix(e.Subpath, "/") || strings.Contains(e.Subpath, "\\") {
+			return fmt.Errorf("%s: subpath must be relative: %s", e.Name, e.Subpath)
+		}
+		for _, part := range strings.Split(e.Subpath, "/") {
+			if part == ".." {
+				return fmt.Errorf("%s: subpath must not contain '..': %s", e.Name, e.Subpath)
+			}
+		}
+	}
+	return nil
+}
+
+// SaveLockfile 写入锁文件（按类别和名称排序，保证输出稳定）
+func SaveLockfile(path string, lf *Lockfile) error {
+	lf.Version = LockfileVersion
+	sort.Slice(lf.Modules, func(i, j int) bool {
+		if lf.Modules[i].Category != lf.Modules[j].Category {
+			return lf.Modules[i].Category < lf.Modules[j].Category
+		}
+		return lf.Modules[i].Name < lf.Modules[j].Name
+	})
+
+	data, err := toml.Marshal(lf)
+	if err != nil {
+		return err
+	}
+
+	header := "# This file is generated by Skill Kit. Do not edit it by hand.\n\n"
+	return os.WriteFile(path, append([]byte(header), data...), 0644)
+}
+
+// Find 查找模块记录
+func (lf *Lockfile) Find(category, name string) *LockEntry {
+	for i := range lf.Modules {
+		if lf.Modules[i].Category == category && lf.Modules[i].Name == name {
+			return &lf.Modules[i]
+		}
+	}
+	return nil
+}
+
+// Upsert 新增或替换模块记录
+func (lf *Lockfile) Upsert(entry LockEntry) {
+	if existing := lf.Find(entry.Category, entry.Name); existing != nil {
+		*existing = entry
+		return
+	}
+	lf.Modules = append(lf.Modules, entry)
+}
+
+// Remove 删除模块记录，返回是否存在
+func (lf *Lockfile) Remove(category, name string) bool {
+	for i := range lf.Modules {
+		if lf.Modules[i].Category == category && lf.Modules[i].Name == name {
+			lf.Modules = append(lf.Modules[:i], lf.Modules[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// NewLockEntry 根据源信息和已发现的技能生成锁记录，地址中的凭据不写入锁文件
+// src.Dir 为克隆/解压/本地源的根目录，用于计算模块在源中的相对路径
+func NewLockEntry(source string, parsed *ParsedSource, skill *DiscoveredSkill, src *FetchedSource) (LockEntry, error) {
+	subpath, err := filepath.Rel(src.Dir, skill.Path)
+	if err != nil {
+		return LockEntry{}, err
+	}
+	if subpath == "." {
+		subpath = ""
+	}
+
+	hash, err := HashDir(skill.Path)
+	if err != nil {
+		return LockEntry{}, err
+	}
+
+	return LockEntry{
+		Name:        skill.Name,
+		Category:    skill.Category,
+		Source:      StripCredentials(source),
+		Type:        parsed.Type,
+		URL:         StripCredentials(parsed.URL),
+		Ref:         parsed.Ref,
+		Subpath:     filepath.ToSlash(subpath),
+		Commit:      src.Commit,
+		SHA256:      src.SHA256,
+		Hash:        hash,
+		InstalledAt: time.Now().UTC().Truncate(time.Second),
+	}, nil
+}
+
+// ParsedSource 将锁记录还原为可获取的源
+func (e *LockEntry) ParsedSource() *ParsedSource {
+	parsed := &ParsedSource{
+		Type: e.Type,
+		URL:  e.URL,
+		Ref:  e.Ref,
+	}
+	if e.Type == "local" || e.Type == "archive" && filepath.IsAbs(e.URL) {
+		parsed.LocalPath = e.URL
+	}
+	return parsed
+}
+
+// PinnedSource 返回固定到锁定提交的源（未记录提交时等同于 ParsedSource）
+// 归档源固定为记录的 SHA-256，内容变化时获取失败
+func (e *LockEntry) PinnedSource() *ParsedSource {
+	parsed := e.ParsedSource()
+	if e.Type == "archive" {
+		parsed.SHA256 = e.SHA256
+	}
+	if e.Commit != "" && e.Type != "local" {
+		parsed.Ref = e.Commit
+	}
+	return parsed
+}
+
+// FindLockedSkill 在已获取的源根目录中定位锁记录对应的模块
+// 返回的技能名称使用锁记录中的安装目录名
+func FindLockedSkill(entry *LockEntry, root string) (*DiscoveredSkill, error) {
+	if err := entry.validate(); err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(root, filepath.FromSlash(entry.Subpath))
+	if !hasSkillFile(dir) {
+		return nil, fmt.Errorf("%s no longer exists in source (expected at '%s')", entry.Name, entry.Subpath)
+	}
+
+	skill := parseSkillFile(dir)
+	if skill == nil || skill.Category != entry.Category {
+		return nil, fmt.Errorf("%s at '%s' is no longer a %s", entry.Name, entry.Subpath, entry.Category)
+	}
+	skill.Name = entry.Name
+	return skill, nil
+}
+
+// RecordDistribution 将当前的默认平台和各模块链接名记录到锁文件
+func RecordDistribution(cfg *Config, lf *Lockfile) {
+	lf.DefaultPlatforms = append([]string(nil), cfg.DefaultPlatforms...)
+	for i := range lf.Modules {
+		entry := &lf.Modules[i]
+		dir := filepath.Join(cfg.RepoPath, entry.Category, entry.Name)
+		modCfg, err := LoadModuleConfig(dir)
+		if err != nil || modCfg.Link.IsEmpty() {
+			entry.Link = nil
+			continue
+		}
+		link := modCfg.Link
+		entry.Link = &link
+	}
+}
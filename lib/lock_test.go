@@ -0,0 +1,154 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLockfileRoundTrip(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, LockfileName)
+
+	lf, err := LoadLockfile(path)
+	if err != nil {
+		t.Fatalf("LoadLockfile on missing file failed: %v", err)
+	}
+	if len(lf.Modules) != 0 {
+		t.Fatalf("expected empty lockfile, got %d modules", len(lf.Modules))
+	}
+
+	lf.Upsert(LockEntry{Name: "zeta", Category: "skill", Type: "github", URL: "https://github.com/o/r.git", Hash: "h1"})
+	lf.Upsert(LockEntry{Name: "alpha", Category: "skill", Type: "github", URL: "https://github.com/o/r.git", Ref: "main", Commit: "abc", Hash: "h2"})
+	lf.Upsert(LockEntry{Name: "zeta", Category: "skill", Type: "github", URL: "https://github.com/o/r.git", Hash: "h3"})
+
+	if err := SaveLockfile(path, lf); err != nil {
+		t.Fatalf("SaveLockfile failed: %v", err)
+	}
+
+	loaded, err := LoadLockfile(path)
+	if err != nil {
+		t.Fatalf("LoadLockfile failed: %v", err)
+	}
+	if len(loaded.Modules) != 2 {
+		t.Fatalf("expected 2 modules, got %d", len(loaded.Modules))
+	}
+	if loaded.Modules[0].Name != "alpha" {
+		t.Errorf("expected modules sorted by name, got %s first", loaded.Modules[0].Name)
+	}
+	if e := loaded.Find("skill", "zeta"); e == nil || e.Hash != "h3" {
+		t.Errorf("expected upserted zeta entry with hash h3, got %+v", e)
+	}
+	if e := loaded.Find("skill", "alpha"); e == nil || e.Ref != "main" || e.Commit != "abc" {
+		t.Errorf("alpha entry not preserved: %+v", e)
+	}
+
+	if !loaded.Remove("skill", "alpha") {
+		t.Error("Remove should report existing entry")
+	}
+	if loaded.Find("skill", "alpha") != nil {
+		t.Error("alpha should have been removed")
+	}
+}
+
+func TestLoadLockfileRejectsUnsafeEntries(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry string
+	}{
+		{"name traversal", `name = "../../escaped"` + "\ncategory = \"skill\""},
+		{"name with separator", `name = "a/b"` + "\ncategory = \"skill\""},
+		{"dot name", `name = ".."` + "\ncategory = \"skill\""},
+		{"unknown category", `name = "ok"` + "\ncategory = \"../skill\""},
+		{"absolute subpath", `name = "ok"` + "\ncategory = \"skill\"\nsubpath = \"/etc\""},
+		{"subpath traversal", `name = "ok"` + "\ncategory = \"skill\"\nsubpath = \"skills/../../outside\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), LockfileName)
+			data := "version = 1\n\n[[module]]\n" + tt.entry + "\ntype = \"local\"\nurl = \"/src\"\nhash = \"h\"\n"
+			if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+				t.Fatalf("failed to write lockfile: %v", err)
+			}
+			if _, err := LoadLockfile(path); err == nil {
+				t.Error("expected hostile lockfile to be rejected")
+			}
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), LockfileName)
+	data := "version = 1\n\n[[module]]\nname = \"ok\"\ncategory = \"agent\"\nsubpath = \"agents/ok\"\ntype = \"local\"\nurl = \"/src\"\nhash = \"h\"\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("failed to write lockfile: %v", err)
+	}
+	if _, err := LoadLockfile(path); err != nil {
+		t.Errorf("valid lockfile rejected: %v", err)
+	}
+}
+
+func TestNewLockEntry(t *testing.T) {
+	root := t.TempDir()
+	skillDir := filepath.Join(root, "skills", "my-skill")
+	if err := os.MkdirAll(skillDir, 0755); err != nil {
+		t.Fatalf("failed to create skill dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte("---\nname: my-skill\n---\n"), 0644); err != nil {
+		t.Fatalf("failed to write SKILL.md: %v", err)
+	}
+
+	parsed := ParseSource("https://github.com/owner/repo/tree/main/skills")
+	skill := &DiscoveredSkill{Name: "my-skill", Category: "skill", Path: skillDir}
+
+	entry, err := NewLockEntry("https://github.com/owner/repo/tree/main/skills", parsed, skill, &FetchedSource{Dir: root, Commit: "deadbeef"})
+	if err != nil {
+		t.Fatalf("NewLockEntry failed: %v", err)
+	}
+	if entry.Subpath != "skills/my-skill" {
+		t.Errorf("expected subpath 'skills/my-skill', got '%s'", entry.Subpath)
+	}
+	if entry.URL != "https://github.com/owner/repo.git" || entry.Ref != "main" {
+		t.Errorf("unexpected url/ref: %s %s", entry.URL, entry.Ref)
+	}
+	if entry.Commit != "deadbeef" {
+		t.Errorf("expected commit 'deadbeef', got '%s'", entry.Commit)
+	}
+	if entry.Hash == "" {
+		t.Error("expected non-empty hash")
+	}
+}
+
+func TestHashDir(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte("a"), 0644)
+
+	h1, err := HashDir(dir)
+	if err != nil {
+		t.Fatalf("HashDir failed: %v", err)
+	}
+	h2, _ := HashDir(dir)
+	if h1 != h2 {
+		t.Error("HashDir should be deterministic")
+	}
+
+	os.MkdirAll(filepath.Join(dir, "scripts"), 0755)
+	os.WriteFile(filepath.Join(dir, "scripts", "run.sh"), []byte("echo"), 0644)
+	h3, _ := HashDir(dir)
+	if h3 == h1 {
+		t.Error("HashDir should change when a file is added")
+	}
+}
+
+func TestLockEntryArchiveSource(t *testing.T) {
+	remote := &LockEntry{Type: "archive", URL: "https://example.com/skills.zip", SHA256: "abc"}
+	if p := remote.ParsedSource(); p.LocalPath != "" || p.SHA256 != "" {
+		t.Errorf("update source should not verify the recorded checksum: %+v", p)
+	}
+	if p := remote.PinnedSource(); p.Type != "archive" || p.SHA256 != "abc" || p.Ref != "" {
+		t.Errorf("unexpected pinned source: %+v", p)
+	}
+
+	local := &LockEntry{Type: "archive", URL: "/srv/skills.tar.gz"}
+	if p := local.PinnedSource(); p.LocalPath != "/srv/skills.tar.gz" {
+		t.Errorf("expected local archive path, got %+v", p)
+	}
+}
@@ -0,0 +1,130 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	toml "github.com/pelletier/go-toml/v2"
+)
+
+// ManifestName 项目清单文件名（位于项目根目录）
+const ManifestName = ".skillkit.toml"
+
+// Manifest 项目清单：声明项目需要的模块及其来源
+type Manifest struct {
+	Platforms []string         `toml:"platforms,omitempty"` // 要链接的平台，未设置时使用 default_platforms
+	Modules   []ManifestModule `toml:"module"`
+}
+
+// ManifestModule 项目需要的单个模块
+type ManifestModule struct {
+	Name   string      `toml:"name"`             // 模块名（仓库中的目录名）
+	Source string      `toml:"source,omitempty"` // 模块不在仓库中时的获取来源，格式同 sk add
+	Ref    string      `toml:"ref,omitempty"`    // 分支/标签/提交
+	SHA256 string      `toml:"sha256,omitempty"` // 归档来源的期望 SHA-256
+	Link   *LinkConfig `toml:"link,omitempty"`   // 链接名配置，只作用于本项目中的链接
+}
+
+// LoadManifest 读取项目清单
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := toml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	seen := make(map[string]bool)
+	for _, mod := range m.Modules {
+		if mod.Name == "" {
+			return nil, fmt.Errorf("%s: module without name", path)
+		}
+		if err := ValidateDirName(mod.Name); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if seen[mod.Name] {
+			return nil, fmt.Errorf("%s: duplicate module %s", path, mod.Name)
+		}
+		seen[mod.Name] = true
+	}
+	return &m, nil
+}
+
+// LinkNames 返回清单中配置了链接名的模块（按目录名）
+func (m *Manifest) LinkNames() map[string]*LinkConfig {
+	links := make(map[string]*LinkConfig)
+	for _, mod := range m.Modules {
+		if mod.Link != nil {
+			links[mod.Name] = mod.Link
+		}
+	}
+	return links
+}
+
+// ParsedSource 返回模块的获取来源，未声明来源时为 nil
+func (m *ManifestModule) ParsedSource(hosts *HostRegistry) *ParsedSource {
+	if m.Source == "" {
+		return nil
+	}
+	parsed := ParseSourceWith(m.Source, hosts)
+	if m.Ref != "" && parsed.Type != "local" && parsed.Type != "archive" {
+		parsed.Ref = m.Ref
+	}
+	if parsed.Type == "archive" {
+		parsed.SHA256 = m.SHA256
+	}
+	return parsed
+}
+
+// PlatformKeys 返回清单要链接的平台（按 key 排序）
+// 依次使用清单中的 platforms、default_platforms、全部平台，只保留配置了项目目录的平台
+func (m *Manifest) PlatformKeys(cfg *Config) ([]string, error) {
+	keys := m.Platforms
+	if len(keys) == 0 {
+		keys = cfg.DefaultPlatforms
+	}
+	if len(keys) == 0 {
+		for key := range cfg.Platforms {
+			keys = append(keys, key)
+		}
+	}
+
+	var result []string
+	for _, key := range keys {
+		p, ok := cfg.Platforms[key]
+		if !ok {
+			if len(m.Platforms) > 0 {
+				return nil, &PlatformNotFoundError{Name: key}
+			}
+			continue
+		}
+		if p.Project != "" {
+			result = append(result, key)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// FindManifestSkill 在已获取的源中定位清单模块
+// 按名称或目录名匹配，不匹配时返回错误而不是改名安装其他模块。返回的技能名称为清单中的模块名
+func FindManifestSkill(skills []*DiscoveredSkill, name string) (*DiscoveredSkill, error) {
+	var available []string
+	for _, skill := range skills {
+		if skill.Name == name || filepath.Base(skill.Path) == name {
+			skill.Name = name
+			return skill, nil
+		}
+		available = append(available, skill.Name)
+	}
+	if len(available) == 0 {
+		return nil, fmt.Errorf("%s not found in source (no modules available)", name)
+	}
+	return nil, fmt.Errorf("%s not found in source (available: %s)", name, strings.Join(available, ", "))
+}
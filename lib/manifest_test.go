@@ -0,0 +1,99 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ManifestName)
+	os.WriteFile(path, []byte(`platforms = ["claude"]
+
+[[module]]
+name = "code-review"
+source = "acme/skills/code-review"
+ref = "v1.2.0"
+
+[module.link.overrides]
+cursor = "review"
+
+[[module]]
+name = "local-only"
+`), 0644)
+
+	m, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("LoadManifest failed: %v", err)
+	}
+	if len(m.Modules) != 2 || m.Modules[0].Link.Overrides["cursor"] != "review" {
+		t.Fatalf("unexpected manifest: %+v", m)
+	}
+
+	parsed := m.Modules[0].ParsedSource(nil)
+	if parsed.Type != "github" || parsed.Ref != "v1.2.0" || parsed.Subpath != "code-review" {
+		t.Errorf("unexpected source: %+v", parsed)
+	}
+	if m.Modules[1].ParsedSource(nil) != nil {
+		t.Error("expected nil source for module without source")
+	}
+
+	os.WriteFile(path, []byte("[[module]]\nname = \"a\"\n[[module]]\nname = \"a\"\n"), 0644)
+	if _, err := LoadManifest(path); err == nil {
+		t.Error("expected error for duplicate module")
+	}
+
+	for _, name := range []string{"../../y", "a/b", ".."} {
+		os.WriteFile(path, []byte("[[module]]\nname = \""+name+"\"\n"), 0644)
+		if _, err := LoadManifest(path); err == nil {
+			t.Errorf("expected error for module name %q", name)
+		}
+	}
+}
+
+func TestManifestPlatformKeys(t *testing.T) {
+	cfg := &Config{
+		Platforms: map[string]Platform{
+			"claude": {Project: ".claude/"},
+			"cursor": {Project: ".cursor/"},
+			"goose":  {Global: "~/.config/goose/"},
+		},
+	}
+
+	keys, _ := (&Manifest{}).PlatformKeys(cfg)
+	if len(keys) != 2 || keys[0] != "claude" || keys[1] != "cursor" {
+		t.Errorf("expected all platforms with a project path, got %v", keys)
+	}
+
+	cfg.DefaultPlatforms = []string{"cursor", "goose"}
+	keys, _ = (&Manifest{}).PlatformKeys(cfg)
+	if len(keys) != 1 || keys[0] != "cursor" {
+		t.Errorf("expected default platforms with a project path, got %v", keys)
+	}
+
+	if _, err := (&Manifest{Platforms: []string{"nope"}}).PlatformKeys(cfg); !IsPlatformNotFound(err) {
+		t.Errorf("expected unknown platform error, got %v", err)
+	}
+}
+
+func TestFindManifestSkill(t *testing.T) {
+	skills := []*DiscoveredSkill{
+		{Name: "Code Review", Path: "/src/skills/code-review"},
+		{Name: "lint", Path: "/src/skills/lint"},
+	}
+	if skill, err := FindManifestSkill(skills, "code-review"); err != nil || skill.Path != "/src/skills/code-review" {
+		t.Errorf("expected match by directory name, got %+v %v", skill, err)
+	}
+	if _, err := FindManifestSkill(skills, "missing"); err == nil {
+		t.Error("expected error for missing module")
+	}
+
+	// 源中只有一个模块时也须名称匹配，不能改名安装无关模块
+	single := []*DiscoveredSkill{{Name: "frontmatter-name", Path: "/src"}}
+	if _, err := FindManifestSkill(single, "renamed"); err == nil {
+		t.Error("expected error when the only module has a different name")
+	}
+	if skill, err := FindManifestSkill(single, "frontmatter-name"); err != nil || skill.Path != "/src" {
+		t.Errorf("expected single skill matched by name, got %+v %v", skill, err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	toml "github.com/pelletier/go-toml/v2"
 )
@@ -15,14 +16,75 @@ type Module struct {
 	Path        string
 	Aliases     map[string]string // platform -> link_name
 	Description string            // 从 SKILL.md/AGENT.md 读取的描述
+	Manifest    *SkillManifest    // SKILL.md/AGENT.md 的 frontmatter，文件不存在时为 nil
 }
 
 // ModuleConfig 模块配置文件 (skillkit.toml)
 type ModuleConfig struct {
-	Link struct {
-		Default   string            `toml:"default"`
-		Overrides map[string]string `toml:"overrides"`
-	} `toml:"link"`
+	Link LinkConfig `toml:"link"`
+}
+
+// LinkConfig 链接名配置（默认名 + 各平台覆盖）
+type LinkConfig struct {
+	Default   string            `toml:"default,omitempty"`
+	Overrides map[string]string `toml:"overrides,omitempty"`
+}
+
+// IsEmpty 检查是否未配置任何链接名
+func (l LinkConfig) IsEmpty() bool {
+	return l.Default == "" && len(l.Overrides) == 0
+}
+
+// LinkName 返回平台的链接名：平台覆盖优先，其次为默认名，都未配置时为空
+func (l *LinkConfig) LinkName(platform string) string {
+	if l == nil {
+		return ""
+	}
+	if name := l.Overrides[platform]; name != "" {
+		return name
+	}
+	return l.Default
+}
+
+// ValidateDirName 检查模块名能否安全地用作仓库中的目录名（单个路径段）
+func ValidateDirName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\\x00") {
+		return fmt.Errorf("invalid module name %q: must be a single directory name", name)
+	}
+	return nil
+}
+
+// ValidateCategory 检查模块类别是否为 skill 或 agent
+func ValidateCategory(category string) error {
+	if category != "skill" && category != "agent" {
+		return fmt.Errorf("invalid category %q: must be skill or agent", category)
+	}
+	return nil
+}
+
+// LoadModuleConfig 读取模块目录下的 skillkit.toml，文件不存在时返回空配置
+func LoadModuleConfig(dir string) (*ModuleConfig, error) {
+	var modCfg ModuleConfig
+	data, err := os.ReadFile(filepath.Join(dir, "skillkit.toml"))
+	if os.IsNotExist(err) {
+		return &modCfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := toml.Unmarshal(data, &modCfg); err != nil {
+		return nil, err
+	}
+	return &modCfg, nil
+}
+
+// SaveModuleConfig 写入模块目录下的 skillkit.toml
+func SaveModuleConfig(dir string, modCfg *ModuleConfig) error {
+	data, err := toml.Marshal(modCfg)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "skillkit.toml"), data, 0644)
 }
 
 // GetLinkName 获取模块在指定平台的链接名
@@ -33,46 +95,43 @@ func (m Module) GetLinkName(platform string) string {
 	return m.Name
 }
 
-// FindModule 查找模块
+// FindModule 查找模块，先在 skill 目录查找，再在 agent 目录查找
 func FindModule(cfg *Config, name string) (*Module, error) {
-	// 先在 skill 目录查找
-	skillPath := filepath.Join(cfg.RepoPath, "skill", name)
-	if info, err := os.Stat(skillPath); err == nil && info.IsDir() {
-		return loadModule(name, "skill", skillPath)
+	for _, category := range []string{"skill", "agent"} {
+		if mod, err := FindModuleInCategory(cfg, category, name); err == nil {
+			return mod, nil
+		}
 	}
+	return nil, &ModuleNotFoundError{Name: name}
+}
 
-	// 再在 agent 目录查找
-	agentPath := filepath.Join(cfg.RepoPath, "agent", name)
-	if info, err := os.Stat(agentPath); err == nil && info.IsDir() {
-		return loadModule(name, "agent", agentPath)
+// FindModuleInCategory 在指定类别中按目录名查找模块，不合法的类别或目录名视为未找到
+func FindModuleInCategory(cfg *Config, category, name string) (*Module, error) {
+	if ValidateCategory(category) != nil || ValidateDirName(name) != nil {
+		return nil, &ModuleNotFoundError{Name: name}
+	}
+	path := filepath.Join(cfg.RepoPath, category, name)
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return loadModule(name, category, path)
 	}
-
 	return nil, &ModuleNotFoundError{Name: name}
 }
 
 // ListModules 列出所有模块
+// 类别目录不存在时视为空，无法读取时返回错误
 func ListModules(cfg *Config) ([]*Module, error) {
 	var modules []*Module
 
-	// 遍历 skill 目录
-	skillDir := filepath.Join(cfg.RepoPath, "skill")
-	if entries, err := os.ReadDir(skillDir); err == nil {
-		for _, entry := range entries {
-			if entry.IsDir() {
-				mod, err := loadModule(entry.Name(), "skill", filepath.Join(skillDir, entry.Name()))
-				if err == nil {
-					modules = append(modules, mod)
-				}
-			}
+	// 依次遍历 skill 和 agent 目录
+	for _, category := range []string{"skill", "agent"} {
+		dir := filepath.Join(cfg.RepoPath, category)
+		entries, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
 		}
-	}
-
-	// 遍历 agent 目录
-	agentDir := filepath.Join(cfg.RepoPath, "agent")
-	if entries, err := os.ReadDir(agentDir); err == nil {
 		for _, entry := range entries {
 			if entry.IsDir() {
-				mod, err := loadModule(entry.Name(), "agent", filepath.Join(agentDir, entry.Name()))
+				mod, err := loadModule(entry.Name(), category, filepath.Join(dir, entry.Name()))
 				if err == nil {
 					modules = append(modules, mod)
 				}
@@ -93,138 +152,61 @@ func loadModule(name, category, path string) (*Module, error) {
 	}
 
 	// 尝试读取 skillkit.toml
-	configPath := filepath.Join(path, "skillkit.toml")
-	if data, err := os.ReadFile(configPath); err == nil {
-		var modCfg ModuleConfig
-		if err := toml.Unmarshal(data, &modCfg); err == nil {
-			if modCfg.Link.Default != "" {
-				mod.Name = modCfg.Link.Default
-			}
-			for platform, alias := range modCfg.Link.Overrides {
-				mod.Aliases[platform] = alias
-			}
+	if modCfg, err := LoadModuleConfig(path); err == nil {
+		if modCfg.Link.Default != "" {
+			mod.Name = modCfg.Link.Default
 		}
-	}
-
-	// 读取描述信息（从 SKILL.md 或 AGENT.md）
-	mod.Description = loadModuleDescription(path, category)
-
-	return mod, nil
-}
-
-// loadModuleDescription 从 SKILL.md/AGENT.md 读取描述
-func loadModuleDescription(path, category string) string {
-	// 尝试读取 SKILL.md 或 AGENT.md
-	var mdPath string
-	if category == "skill" {
-		mdPath = filepath.Join(path, "SKILL.md")
-	} else {
-		mdPath = filepath.Join(path, "AGENT.md")
-	}
-
-	data, err := os.ReadFile(mdPath)
-	if err != nil {
-		return ""
-	}
-
-	content := string(data)
-	lines := splitLines(content)
-
-	// 查找 description 字段（在 frontmatter 中）或第一段描述
-	inFrontmatter := false
-	for _, line := range lines {
-		if line == "---" {
-			inFrontmatter = !inFrontmatter
-			continue
-		}
-		if inFrontmatter {
-			// 查找 description: 字段
-			if len(line) > 12 && line[:12] == "description:" {
-				desc := line[12:]
-				// 去除引号和空格
-				desc = trimQuotes(desc)
-				return desc // 返回完整描述，不截断
-			}
+		for platform, alias := range modCfg.Link.Overrides {
+			mod.Aliases[platform] = alias
 		}
 	}
 
-	// 如果没有 frontmatter，返回第一行非空非标题内容
-	for _, line := range lines {
-		if line == "" || line[0] == '#' || line == "---" {
-			continue
-		}
-		return line // 返回完整描述，不截断
-	}
-
-	return ""
-}
-
-func splitLines(s string) []string {
-	var lines []string
-	start := 0
-	for i := 0; i < len(s); i++ {
-		if s[i] == '\n' {
-			lines = append(lines, s[start:i])
-			start = i + 1
-		}
-	}
-	if start < len(s) {
-		lines = append(lines, s[start:])
-	}
-	return lines
-}
-
-func trimQuotes(s string) string {
-	s = trimSpace(s)
-	if len(s) >= 2 {
-		if (s[0] == '"' && s[len(s)-1] == '"') || (s[0] == '\'' && s[len(s)-1] == '\'') {
-			return s[1 : len(s)-1]
-		}
+	// 读取描述信息（从 SKILL.md 或 AGENT.md）
+	if m, _ := LoadSkillManifest(path, category); m != nil {
+		mod.Manifest = m
+		mod.Description = m.Description
 	}
-	return s
-}
 
-func trimSpace(s string) string {
-	start := 0
-	end := len(s)
-	for start < end && (s[start] == ' ' || s[start] == '\t') {
-		start++
-	}
-	for end > start && (s[end-1] == ' ' || s[end-1] == '\t') {
-		end--
-	}
-	return s[start:end]
+	return mod, nil
 }
 
-// GetSyncedPlatformKeys 获取已同步的平台 key 列表
-func GetSyncedPlatformKeys(cfg *Config, mod *Module) []string {
+// GetSyncedPlatformKeys 获取已同步的平台 key 列表（任一作用域已分发即算，默认全局）
+func GetSyncedPlatformKeys(cfg *Config, mod *Module, scopes ...string) []string {
 	var keys []string
 	for key, p := range cfg.Platforms {
-		targetDir := ResolvePath(p.Global, p.GetCategoryDir(mod.Category))
-		targetPath := targetDir + "/" + mod.GetLinkName(key)
-		if IsSymlink(targetPath) {
-			keys = append(keys, key)
+		for _, scope := range orGlobal(scopes) {
+			if target := ModuleTarget(p, key, mod, scope); target != "" && IsDistributed(target) {
+				keys = append(keys, key)
+				break
+			}
 		}
 	}
 	return keys
 }
 
-// GetLinkStatus 获取模块的链接状态
-func GetLinkStatus(cfg *Config, mod *Module) []string {
+// GetLinkStatus 获取模块的链接状态（默认全局，项目链接标注 [project]）
+func GetLinkStatus(cfg *Config, mod *Module, scopes ...string) []string {
 	var status []string
 
-	for name, p := range cfg.Platforms {
-		ln := mod.GetLinkName(name)
-		targetDir := ResolvePath(p.Global, p.GetCategoryDir(mod.Category))
-		targetPath := filepath.Join(targetDir, ln)
-
-		if IsSymlink(targetPath) {
-			realPath, _ := ReadSymlink(targetPath)
-			if realPath == mod.Path {
+	for _, ls := range ModuleLinkStates(cfg, mod, scopes...) {
+		name := ls.Platform
+		if ls.Scope == ScopeProject {
+			name += " [project]"
+		}
+		switch ls.State {
+		case LinkStateOK:
+			switch ls.Mode {
+			case LinkModeSymlink:
 				status = append(status, fmt.Sprintf("%s ✓", name))
-			} else {
-				status = append(status, fmt.Sprintf("%s ✗ (broken)", name))
+			case LinkModeRelative:
+				status = append(status, fmt.Sprintf("%s ✓ (relative)", name))
+			default:
+				status = append(status, fmt.Sprintf("%s ✓ (copy)", name))
 			}
+		case LinkStateBroken:
+			status = append(status, fmt.Sprintf("%s ✗ (broken)", name))
+		case LinkStateOutdated, LinkStateModified:
+			status = append(status, fmt.Sprintf("%s ~ (copy %s)", name, ls.State))
 		}
 	}
 
@@ -146,3 +146,30 @@ cursor = "cursor-alias"
 		t.Errorf("expected default name for copilot, got '%s'", mod.GetLinkName("copilot"))
 	}
 }
+
+func TestFindModuleInCategory(t *testing.T) {
+	cfg := &Config{RepoPath: t.TempDir()}
+	os.MkdirAll(filepath.Join(cfg.RepoPath, "agent", "foo"), 0755)
+
+	if _, err := FindModuleInCategory(cfg, "skill", "foo"); err == nil {
+		t.Error("expected agent module not to be found as a skill")
+	}
+	if mod, err := FindModuleInCategory(cfg, "agent", "foo"); err != nil || mod.Category != "agent" {
+		t.Errorf("expected agent module, got %+v, %v", mod, err)
+	}
+	if _, err := FindModuleInCategory(cfg, "agent", "../agent/foo"); err == nil {
+		t.Error("expected a path as module name to be rejected")
+	}
+}
+
+func TestListModulesReportsUnreadablePool(t *testing.T) {
+	cfg := &Config{RepoPath: t.TempDir()}
+	if modules, err := ListModules(cfg); err != nil || len(modules) != 0 {
+		t.Errorf("expected an empty pool without error, got %v, %v", modules, err)
+	}
+
+	os.WriteFile(filepath.Join(cfg.RepoPath, "skill"), []byte("not a directory"), 0644)
+	if _, err := ListModules(cfg); err == nil {
+		t.Error("expected an error when the skill directory cannot be read")
+	}
+}
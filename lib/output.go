@@ -0,0 +1,84 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 输出格式
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// ParseOutputFlags 从参数中取出全局输出格式选项 (--json, --format <fmt>, --format=<fmt>)
+// 返回格式和剩余参数
+func ParseOutputFlags(args []string) (string, []string, error) {
+	format := FormatText
+	var rest []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--json":
+			format = FormatJSON
+		case arg == "--format":
+			if i+1 >= len(args) {
+				return "", nil, fmt.Errorf("--format requires a value (text, json, yaml)")
+			}
+			format = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--format="):
+			format = strings.TrimPrefix(arg, "--format=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+
+	switch format {
+	case FormatText, FormatJSON, FormatYAML:
+		return format, rest, nil
+	}
+	return "", nil, fmt.Errorf("unknown output format: %s (expected text, json or yaml)", format)
+}
+
+// WriteReport 以 JSON 或 YAML 输出报告
+// YAML 由 JSON 转换而来，字段名和顺序与 JSON 保持一致
+func WriteReport(w io.Writer, format string, report any) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if format != FormatYAML {
+		_, err = fmt.Fprintf(w, "%s\n", data)
+		return err
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	clearYAMLStyle(&node)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// clearYAMLStyle 去掉从 JSON 继承的 flow 风格和引号，输出块风格 YAML
+// 需要引号的字符串（如 "true"）由编码器自动加引号
+func clearYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearYAMLStyle(child)
+	}
+}
@@ -0,0 +1,225 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// 分发操作类型
+const (
+	ActionCreate  = "CREATE"  // 目标不存在
+	ActionUpdate  = "UPDATE"  // 替换已有的软链接或副本
+	ActionBlocked = "BLOCKED" // 目标为实体文件/目录或被修改的副本
+)
+
+// ErrAborted 原子模式下前面的操作失败，后续操作未执行
+var ErrAborted = errors.New("skipped: an earlier operation failed")
+
+// LinkOp 单个分发操作
+type LinkOp struct {
+	Module   string `json:"module"`
+	Platform string `json:"platform"` // 平台 key
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	Mode     string `json:"mode"`   // LinkMode*
+	Action   string `json:"action"` // Action*
+}
+
+// Plan 一组待执行的分发操作
+type Plan struct {
+	Ops []LinkOp
+}
+
+// OpResult 单个操作的执行结果
+type OpResult struct {
+	Op         LinkOp
+	Err        error
+	RolledBack bool // 已恢复到执行前的状态
+}
+
+// PlanLinks 计算模块到平台指定作用域（默认全局）的分发操作（按作用域、模块、平台 key 排序）
+// 未配置项目目录的平台在项目作用域中跳过
+func PlanLinks(modules []*Module, platforms map[string]Platform, scopes ...string) *Plan {
+	keys := make([]string, 0, len(platforms))
+	for key := range platforms {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	plan := &Plan{}
+	for _, scope := range orGlobal(scopes) {
+		for _, mod := range modules {
+			for _, key := range keys {
+				p := platforms[key]
+				if base := p.GetBaseDir(scope); base != "" {
+					plan.Add(mod, key, p, base, p.LinkName(key, mod, scope))
+				}
+			}
+		}
+	}
+	return plan
+}
+
+// Add 添加一个分发操作。baseDir 为平台的 global 或 project 目录，linkName 为空时使用模块的链接名
+func (plan *Plan) Add(mod *Module, platKey string, p Platform, baseDir, linkName string) {
+	if linkName == "" {
+		linkName = mod.GetLinkName(platKey)
+	}
+	target := filepath.Join(ResolvePath(baseDir, p.GetCategoryDir(mod.Category)), linkName)
+	plan.Ops = append(plan.Ops, LinkOp{
+		Module:   mod.Name,
+		Platform: platKey,
+		Source:   mod.Path,
+		Target:   target,
+		Mode:     p.GetLinkMode(),
+		Action:   planAction(target),
+	})
+}
+
+func planAction(target string) string {
+	if _, err := os.Lstat(target); os.IsNotExist(err) {
+		return ActionCreate
+	}
+	if IsSymlink(target) || isReplaceableCopy(target) {
+		return ActionUpdate
+	}
+	return ActionBlocked
+}
+
+// isReplaceableCopy 检查目标是否为未被修改的托管副本
+func isReplaceableCopy(target string) bool {
+	marker, err := ReadCopyMarker(target)
+	if err != nil || IsSymlink(target) {
+		return false
+	}
+	hash, err := HashDir(target)
+	if err != nil {
+		return false
+	}
+	modified, err := copyModified(target, marker, hash)
+	return err == nil && !modified
+}
+
+// ApplyPlan 依次执行分发操作。每个目标在修改前先备份（旧软链接的指向或移开的副本）。
+// 操作失败时恢复该目标；atomic 为 true 时还会回滚此前所有已完成的操作，并跳过剩余操作。
+func ApplyPlan(plan *Plan, atomic bool) []OpResult {
+	results := make([]OpResult, 0, len(plan.Ops))
+	var backups []*targetBackup
+
+	for i, op := range plan.Ops {
+		backup, err := backupTarget(op.Target)
+		if err == nil {
+			if err = Distribute(op.Source, op.Target, op.Mode); err != nil {
+				if rerr := backup.restore(); rerr != nil {
+					err = fmt.Errorf("%v (rollback failed: %v)", err, rerr)
+				}
+			}
+		}
+		results = append(results, OpResult{Op: op, Err: err, RolledBack: err != nil && backup != nil})
+
+		if err != nil && atomic {
+			for j := len(backups) - 1; j >= 0; j-- {
+				if rerr := backups[j].restore(); rerr != nil {
+					results[j].Err = fmt.Errorf("rollback failed: %v", rerr)
+				}
+				results[j].RolledBack = true
+			}
+			for _, rest := range plan.Ops[i+1:] {
+				results = append(results, OpResult{Op: rest, Err: ErrAborted})
+			}
+			return results
+		}
+		if err == nil {
+			backups = append(backups, backup)
+		} else {
+			backups = append(backups, nil)
+		}
+	}
+
+	for _, b := range backups {
+		b.discard()
+	}
+	return results
+}
+
+// targetBackup 目标被修改前的状态
+type targetBackup struct {
+	target   string
+	linkDest string // 原软链接的指向
+	copyPath string // 原副本被移到的位置
+}
+
+// backupTarget 记录目标当前状态并将其移开，为分发腾出位置
+func backupTarget(target string) (*targetBackup, error) {
+	target = ResolvePath(target)
+	b := &targetBackup{target: target}
+
+	info, err := os.Lstat(target)
+	if os.IsNotExist(err) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		dest, err := os.Readlink(target)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Remove(target); err != nil {
+			return nil, fmt.Errorf("failed to remove existing symlink: %v", err)
+		}
+		b.linkDest = dest
+		return b, nil
+	}
+
+	if IsManagedCopy(target) {
+		if !isReplaceableCopy(target) {
+			return nil, fmt.Errorf("copy has local changes, refusing to replace: %s", target)
+		}
+		b.copyPath = filepath.Join(filepath.Dir(target), "."+filepath.Base(target)+".skillkit-bak")
+		os.RemoveAll(b.copyPath)
+		if err := os.Rename(target, b.copyPath); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+
+	if info.IsDir() {
+		return nil, fmt.Errorf("target is a real directory (not symlink): %s", target)
+	}
+	return nil, fmt.Errorf("target is a real file (not symlink): %s", target)
+}
+
+// restore 删除分发结果并恢复目标原状态
+func (b *targetBackup) restore() error {
+	if b == nil {
+		return nil
+	}
+	if IsSymlink(b.target) {
+		if err := os.Remove(b.target); err != nil {
+			return err
+		}
+	} else if err := os.RemoveAll(b.target); err != nil {
+		return err
+	}
+
+	switch {
+	case b.linkDest != "":
+		return os.Symlink(b.linkDest, b.target)
+	case b.copyPath != "":
+		return os.Rename(b.copyPath, b.target)
+	}
+	return nil
+}
+
+// discard 操作成功后删除备份
+func (b *targetBackup) discard() {
+	if b != nil && b.copyPath != "" {
+		os.RemoveAll(b.copyPath)
+	}
+}
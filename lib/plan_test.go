@@ -0,0 +1,137 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setupPlanTest 创建两个模块和两个平台，返回模块与平台
+func setupPlanTest(t *testing.T) ([]*Module, map[string]Platform) {
+	t.Helper()
+	tmp := t.TempDir()
+
+	var modules []*Module
+	for _, name := range []string{"alpha", "beta"} {
+		dir := filepath.Join(tmp, "repo", "skill", name)
+		os.MkdirAll(dir, 0755)
+		os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte(name), 0644)
+		modules = append(modules, &Module{Name: name, Path: dir, Category: "skill"})
+	}
+
+	platforms := map[string]Platform{
+		"one": {Name: "One", Global: filepath.Join(tmp, "one"), SkillDir: "skills"},
+		"two": {Name: "Two", Global: filepath.Join(tmp, "two"), SkillDir: "skills", LinkMode: LinkModeCopy},
+	}
+	return modules, platforms
+}
+
+func TestPlanLinks(t *testing.T) {
+	modules, platforms := setupPlanTest(t)
+
+	// 已有软链接 → UPDATE，实体目录 → BLOCKED
+	os.MkdirAll(filepath.Join(platforms["one"].Global, "skills"), 0755)
+	os.Symlink("/nowhere", filepath.Join(platforms["one"].Global, "skills", "alpha"))
+	os.MkdirAll(filepath.Join(platforms["two"].Global, "skills", "beta"), 0755)
+
+	plan := PlanLinks(modules, platforms)
+	if len(plan.Ops) != 4 {
+		t.Fatalf("expected 4 ops, got %d", len(plan.Ops))
+	}
+
+	expected := []struct{ module, platform, action string }{
+		{"alpha", "one", ActionUpdate},
+		{"alpha", "two", ActionCreate},
+		{"beta", "one", ActionCreate},
+		{"beta", "two", ActionBlocked},
+	}
+	for i, e := range expected {
+		op := plan.Ops[i]
+		if op.Module != e.module || op.Platform != e.platform || op.Action != e.action {
+			t.Errorf("op %d = %s/%s/%s, expected %s/%s/%s", i, op.Module, op.Platform, op.Action, e.module, e.platform, e.action)
+		}
+	}
+	if plan.Ops[1].Mode != LinkModeCopy {
+		t.Errorf("expected copy mode for platform two, got %s", plan.Ops[1].Mode)
+	}
+}
+
+func TestApplyPlanRestoresFailedTarget(t *testing.T) {
+	modules, platforms := setupPlanTest(t)
+	plan := PlanLinks(modules[:1], platforms)
+
+	// alpha → one 原本指向旧位置；源被删除后分发失败，应恢复原软链接
+	oldTarget := plan.Ops[0].Target
+	os.MkdirAll(filepath.Dir(oldTarget), 0755)
+	os.Symlink("/old/alpha", oldTarget)
+	plan.Ops[0].Source = filepath.Join(t.TempDir(), "missing")
+
+	results := ApplyPlan(plan, false)
+	if results[0].Err == nil || !results[0].RolledBack {
+		t.Fatalf("expected failed and rolled back op, got %+v", results[0])
+	}
+	if dest, _ := os.Readlink(oldTarget); dest != "/old/alpha" {
+		t.Errorf("expected old symlink restored, got %q", dest)
+	}
+
+	// 非原子模式下其他操作照常执行
+	if results[1].Err != nil || !IsManagedCopy(plan.Ops[1].Target) {
+		t.Errorf("expected second op to succeed, got %v", results[1].Err)
+	}
+}
+
+func TestApplyPlanAtomic(t *testing.T) {
+	modules, platforms := setupPlanTest(t)
+	plan := PlanLinks(modules, platforms)
+
+	// 第一个目标已有旧软链接，第三个操作失败
+	first := plan.Ops[0].Target
+	os.MkdirAll(filepath.Dir(first), 0755)
+	os.Symlink("/old/alpha", first)
+	plan.Ops[2].Source = filepath.Join(t.TempDir(), "missing")
+
+	results := ApplyPlan(plan, true)
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+	if !results[0].RolledBack || !results[1].RolledBack {
+		t.Error("expected completed ops to be rolled back")
+	}
+	if results[3].Err != ErrAborted {
+		t.Errorf("expected remaining op to be skipped, got %v", results[3].Err)
+	}
+
+	if dest, _ := os.Readlink(first); dest != "/old/alpha" {
+		t.Errorf("expected old symlink restored, got %q", dest)
+	}
+	for _, op := range plan.Ops[1:] {
+		if _, err := os.Lstat(op.Target); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be absent after rollback", op.Target)
+		}
+	}
+}
+
+func TestApplyPlanRestoresReplacedCopy(t *testing.T) {
+	modules, platforms := setupPlanTest(t)
+	plan := PlanLinks(modules[:1], map[string]Platform{"two": platforms["two"]})
+	target := plan.Ops[0].Target
+
+	if err := Distribute(modules[0].Path, target, LinkModeCopy); err != nil {
+		t.Fatalf("Distribute failed: %v", err)
+	}
+	before, _ := ReadCopyMarker(target)
+
+	plan.Ops[0].Source = filepath.Join(t.TempDir(), "missing")
+	results := ApplyPlan(plan, false)
+	if results[0].Err == nil {
+		t.Fatal("expected failure")
+	}
+
+	after, err := ReadCopyMarker(target)
+	if err != nil || after.Hash != before.Hash {
+		t.Errorf("expected original copy restored, got %+v, %v", after, err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(target), ".alpha.skillkit-bak")); !os.IsNotExist(err) {
+		t.Error("expected backup to be cleaned up")
+	}
+}
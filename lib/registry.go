@@ -0,0 +1,130 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// RegistryIndexName sk registry build 默认写入的文件名
+const RegistryIndexName = "index.json"
+
+// RegistryOptions 生成索引的选项
+type RegistryOptions struct {
+	// SourceBase 仓库本身发布到的来源（如 team/agent-pool），没有远程来源的模块
+	// 使用 <SourceBase>/<category>/<目录名>
+	SourceBase string
+}
+
+// RegistrySkip 未写入索引的模块
+type RegistrySkip struct {
+	Name     string
+	Category string
+	Reason   string
+}
+
+// BuildRegistry 从仓库中的模块生成技能索引（按类别、名称排序）
+// 来源优先使用锁文件中记录的远程来源，其次使用 SourceBase；都没有的模块被跳过
+func BuildRegistry(cfg *Config, opts RegistryOptions) (*SkillIndex, []RegistrySkip, error) {
+	modules, err := ListModules(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	lock, err := LoadLockfile(LockfilePath(cfg))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	index := &SkillIndex{Version: IndexFormatVersion, Skills: []IndexEntry{}}
+	var skipped []RegistrySkip
+	for _, mod := range modules {
+		dirName := filepath.Base(mod.Path)
+		entry := IndexEntry{
+			Name:        dirName,
+			Description: mod.Description,
+			Category:    mod.Category,
+		}
+		if m := mod.Manifest; m != nil {
+			entry.Tags = m.Tags
+			entry.Version = m.Version
+		}
+
+		if le := lock.Find(mod.Category, dirName); le != nil && le.hasRemoteGitSource() {
+			entry.Source = lockSource(le)
+			entry.Commit = le.Commit
+		} else if opts.SourceBase != "" {
+			entry.Source = strings.TrimSuffix(opts.SourceBase, "/") + "/" + mod.Category + "/" + dirName
+		} else {
+			skipped = append(skipped, RegistrySkip{Name: dirName, Category: mod.Category, Reason: "no remote source (set --source-base)"})
+			continue
+		}
+
+		if entry.Hash, err = HashDir(mod.Path); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", mod.Path, err)
+		}
+		index.Skills = append(index.Skills, entry)
+	}
+
+	sort.Slice(index.Skills, func(i, j int) bool {
+		a, b := index.Skills[i], index.Skills[j]
+		if a.Category != b.Category {
+			return a.Category > b.Category // skill 在 agent 之前
+		}
+		return a.Name < b.Name
+	})
+	return index, skipped, nil
+}
+
+// hasRemoteGitSource 检查锁记录是否来自远程 Git 仓库
+// 本地目录和归档记录的是发布者机器上的路径，对索引使用者无用，不写入索引
+func (le *LockEntry) hasRemoteGitSource() bool {
+	if le.Type == "local" || le.Type == "archive" {
+		return false
+	}
+	return !strings.HasPrefix(le.URL, "file://") && !filepath.IsAbs(le.URL)
+}
+
+// lockSource 返回锁记录对应的来源，用 --ref 安装时把 ref 加回来源
+func lockSource(le *LockEntry) string {
+	if le.Ref != "" && !strings.HasSuffix(le.Source, "@"+le.Ref) {
+		return le.Source + "@" + le.Ref
+	}
+	return le.Source
+}
+
+// MarshalIndex 把索引编码为带缩进的 JSON
+func MarshalIndex(index *SkillIndex) ([]byte, error) {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// RegistryHandler 提供索引的 HTTP 处理器，/ 和 /index.json 返回 load 的结果
+func RegistryHandler(load func() ([]byte, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" && r.URL.Path != "/"+RegistryIndexName {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		data, err := load()
+		if err != nil {
+			// 错误中可能含有本地路径，只记录到服务端
+			fmt.Fprintf(os.Stderr, "%s %s %s: %v\n", Red(IconError), r.Method, r.URL.Path, err)
+			http.Error(w, "failed to build index", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+}
@@ -0,0 +1,140 @@
+package lib
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupRegistryRepo 创建包含远程来源、本地来源和本地归档来源模块的仓库
+func setupRegistryRepo(t *testing.T) *Config {
+	t.Helper()
+	cfg := &Config{RepoPath: t.TempDir()}
+	modules := map[string]string{
+		"skill/pdf-tools": "---\nname: pdf-tools\ndescription: Extract text from PDFs.\nversion: 1.2.0\ntags: [pdf]\n---\n",
+		"skill/notes":     "---\nname: notes\ndescription: Team note taking.\n---\n",
+		"agent/reviewer":  "---\nname: reviewer\ndescription: Reviews pull requests.\n---\n",
+	}
+	for dir, content := range modules {
+		path := filepath.Join(cfg.RepoPath, dir)
+		os.MkdirAll(path, 0755)
+		os.WriteFile(filepath.Join(path, SkillFileName(filepath.Dir(dir))), []byte(content), 0644)
+	}
+
+	lock := &Lockfile{Modules: []LockEntry{
+		{Name: "pdf-tools", Category: "skill", Source: "acme/skills", Type: "github", Ref: "v1.2.0", Commit: "abc123"},
+		{Name: "notes", Category: "skill", Source: "/home/me/notes", Type: "local"},
+		{Name: "reviewer", Category: "agent", Source: "/home/me/reviewer.zip", Type: "archive", URL: "/home/me/reviewer.zip"},
+	}}
+	if err := SaveLockfile(LockfilePath(cfg), lock); err != nil {
+		t.Fatalf("SaveLockfile failed: %v", err)
+	}
+	return cfg
+}
+
+func TestBuildRegistry(t *testing.T) {
+	cfg := setupRegistryRepo(t)
+
+	index, skipped, err := BuildRegistry(cfg, RegistryOptions{})
+	if err != nil {
+		t.Fatalf("BuildRegistry failed: %v", err)
+	}
+	if len(index.Skills) != 1 || len(skipped) != 2 {
+		t.Fatalf("expected 1 entry and 2 skipped, got %+v / %+v", index.Skills, skipped)
+	}
+	entry := index.Skills[0]
+	if entry.Name != "pdf-tools" || entry.Source != "acme/skills@v1.2.0" || entry.Commit != "abc123" {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	if entry.Version != "1.2.0" || len(entry.Tags) != 1 || entry.Description != "Extract text from PDFs." {
+		t.Errorf("frontmatter not collected: %+v", entry)
+	}
+	if hash, _ := HashDir(filepath.Join(cfg.RepoPath, "skill", "pdf-tools")); entry.Hash != hash {
+		t.Errorf("hash = %s, want %s", entry.Hash, hash)
+	}
+
+	index, skipped, err = BuildRegistry(cfg, RegistryOptions{SourceBase: "team/agent-pool/"})
+	if err != nil || len(skipped) != 0 || len(index.Skills) != 3 {
+		t.Fatalf("BuildRegistry with source base: %+v, %+v, %v", index, skipped, err)
+	}
+	var names, sources []string
+	for _, e := range index.Skills {
+		names = append(names, e.Category+"/"+e.Name)
+		sources = append(sources, e.Source)
+	}
+	wantNames := []string{"skill/notes", "skill/pdf-tools", "agent/reviewer"}
+	wantSources := []string{"team/agent-pool/skill/notes", "acme/skills@v1.2.0", "team/agent-pool/agent/reviewer"}
+	for i := range wantNames {
+		if names[i] != wantNames[i] || sources[i] != wantSources[i] {
+			t.Errorf("entries = %v %v, want %v %v", names, sources, wantNames, wantSources)
+			break
+		}
+	}
+}
+
+func TestRegistryServeRoundTrip(t *testing.T) {
+	cfg := setupRegistryRepo(t)
+	load := func() ([]byte, error) {
+		index, _, err := BuildRegistry(cfg, RegistryOptions{SourceBase: "team/agent-pool"})
+		if err != nil {
+			return nil, err
+		}
+		return MarshalIndex(index)
+	}
+	server := httptest.NewServer(RegistryHandler(load))
+	defer server.Close()
+
+	client := &Config{RepoPath: t.TempDir()}
+	index, stale, err := LoadIndex(client, IndexConfig{Name: "team", URL: server.URL + "/" + RegistryIndexName}, false)
+	if err != nil || stale {
+		t.Fatalf("LoadIndex failed: %v (stale=%v)", err, stale)
+	}
+	results := SearchIndex(index, "team", "pull requests")
+	if len(results) != 1 || results[0].Name != "reviewer" || results[0].Category != "agent" {
+		t.Errorf("unexpected results: %+v", results)
+	}
+
+	for path, want := range map[string]int{"/": http.StatusOK, "/other.json": http.StatusNotFound} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("GET %s = %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+	resp, err := http.Post(server.URL+"/"+RegistryIndexName, "application/json", nil)
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusMethodNotAllowed {
+			t.Errorf("POST = %d, want 405", resp.StatusCode)
+		}
+	}
+}
+
+func TestRegistryHandlerHidesLoadErrors(t *testing.T) {
+	load := func() ([]byte, error) {
+		return nil, errors.New("open /home/me/agent/skill: permission denied")
+	}
+	server := httptest.NewServer(RegistryHandler(load))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/" + RegistryIndexName)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", resp.StatusCode)
+	}
+	if strings.Contains(string(body), "/home/me") {
+		t.Errorf("response leaks the load error: %s", body)
+	}
+}
@@ -0,0 +1,102 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+// RestoreModule 按锁记录把模块恢复到仓库
+// root 为已获取并检出到锁定提交的源根目录。
+// 返回执行的动作："installed", "updated" 或 "unchanged"。
+func RestoreModule(cfg *Config, entry *LockEntry, root string) (string, error) {
+	skill, err := FindLockedSkill(entry, root)
+	if err != nil {
+		return "", err
+	}
+
+	// 校验内容与锁文件一致
+	hash, err := HashDir(skill.Path)
+	if err != nil {
+		return "", err
+	}
+	if entry.Hash != "" && hash != entry.Hash {
+		return "", fmt.Errorf("%s: content hash mismatch (expected %s, got %s)", entry.Name, shortHash(entry.Hash), shortHash(hash))
+	}
+
+	action := "installed"
+	targetDir := filepath.Join(cfg.RepoPath, entry.Category, entry.Name)
+	if _, err := os.Stat(targetDir); err == nil {
+		changes, err := DiffDirs(targetDir, skill.Path)
+		if err != nil {
+			return "", err
+		}
+		if onlyLinkConfigChanged(changes) {
+			action = "unchanged"
+		} else {
+			if err := UpdateSkill(skill, cfg); err != nil {
+				return "", err
+			}
+			action = "updated"
+		}
+	} else if err := InstallSkill(skill, cfg); err != nil {
+		return "", err
+	}
+
+	if entry.Link != nil {
+		if err := ApplyLinkConfig(targetDir, entry.Link); err != nil {
+			return "", fmt.Errorf("%s: failed to apply link config: %w", entry.Name, err)
+		}
+	}
+
+	return action, nil
+}
+
+// IsModuleIntact 检查已安装模块的内容是否与锁记录一致
+// 恢复时写入的本地 skillkit.toml（链接名）不算改动
+func IsModuleIntact(cfg *Config, entry *LockEntry) bool {
+	dir := filepath.Join(cfg.RepoPath, entry.Category, entry.Name)
+	if hash, err := HashDir(dir); err != nil || hash == entry.Hash {
+		return err == nil
+	}
+	hash, err := hashDir(dir, map[string]bool{"skillkit.toml": true})
+	return err == nil && hash == entry.Hash
+}
+
+// onlyLinkConfigChanged 检查差异是否仅限于本地 skillkit.toml
+func onlyLinkConfigChanged(changes []FileChange) bool {
+	for _, c := range changes {
+		if c.Path != "skillkit.toml" {
+			return false
+		}
+	}
+	return true
+}
+
+// ApplyLinkConfig 将链接名配置写入模块的 skillkit.toml（已一致时不写入）
+func ApplyLinkConfig(dir string, link *LinkConfig) error {
+	modCfg, err := LoadModuleConfig(dir)
+	if err != nil {
+		return err
+	}
+	if modCfg.Link.Default == link.Default && reflect.DeepEqual(normalizeOverrides(modCfg.Link.Overrides), normalizeOverrides(link.Overrides)) {
+		return nil
+	}
+	modCfg.Link = *link
+	return SaveModuleConfig(dir, modCfg)
+}
+
+func normalizeOverrides(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
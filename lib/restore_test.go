@@ -0,0 +1,97 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRestoreModule(t *testing.T) {
+	source := t.TempDir()
+	skillDir := filepath.Join(source, "skills", "my-skill")
+	os.MkdirAll(skillDir, 0755)
+	os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte("---\nname: my-skill\n---\n"), 0644)
+
+	hash, err := HashDir(skillDir)
+	if err != nil {
+		t.Fatalf("HashDir failed: %v", err)
+	}
+
+	cfg := &Config{RepoPath: t.TempDir()}
+	entry := &LockEntry{
+		Name:     "my-skill",
+		Category: "skill",
+		Type:     "local",
+		URL:      source,
+		Subpath:  "skills/my-skill",
+		Hash:     hash,
+		Link:     &LinkConfig{Overrides: map[string]string{"claude": "mine"}},
+	}
+
+	action, err := RestoreModule(cfg, entry, source)
+	if err != nil {
+		t.Fatalf("RestoreModule failed: %v", err)
+	}
+	if action != "installed" {
+		t.Errorf("expected action 'installed', got '%s'", action)
+	}
+
+	mod, err := FindModule(cfg, "my-skill")
+	if err != nil {
+		t.Fatalf("restored module not found: %v", err)
+	}
+	if mod.GetLinkName("claude") != "mine" {
+		t.Errorf("expected recorded alias to be applied, got '%s'", mod.GetLinkName("claude"))
+	}
+	if !IsModuleIntact(cfg, entry) {
+		t.Error("module with applied link config should be intact")
+	}
+	os.WriteFile(filepath.Join(mod.Path, "SKILL.md"), []byte("edited"), 0644)
+	if IsModuleIntact(cfg, entry) {
+		t.Error("edited module should not be intact")
+	}
+
+	// 再次恢复应还原内容
+	action, err = RestoreModule(cfg, entry, source)
+	if err != nil {
+		t.Fatalf("second RestoreModule failed: %v", err)
+	}
+	if action != "updated" {
+		t.Errorf("expected action 'updated' on second restore, got '%s'", action)
+	}
+	action, _ = RestoreModule(cfg, entry, source)
+	if action != "unchanged" {
+		t.Errorf("expected action 'unchanged' on third restore, got '%s'", action)
+	}
+}
+
+func TestRestoreModuleRejectsHashMismatch(t *testing.T) {
+	source := t.TempDir()
+	os.WriteFile(filepath.Join(source, "SKILL.md"), []byte("tampered"), 0644)
+
+	cfg := &Config{RepoPath: t.TempDir()}
+	entry := &LockEntry{Name: "my-skill", Category: "skill", Type: "local", URL: source, Hash: "0000"}
+
+	if _, err := RestoreModule(cfg, entry, source); err == nil {
+		t.Error("expected error on content hash mismatch")
+	}
+	if _, err := os.Stat(filepath.Join(cfg.RepoPath, "skill", "my-skill")); !os.IsNotExist(err) {
+		t.Error("module should not be installed when hash does not match")
+	}
+}
+
+func TestRestoreModuleRejectsEscapingName(t *testing.T) {
+	source := t.TempDir()
+	os.WriteFile(filepath.Join(source, "SKILL.md"), []byte("---\nname: my-skill\n---\n"), 0644)
+
+	base := t.TempDir()
+	cfg := &Config{RepoPath: filepath.Join(base, "repo")}
+	entry := &LockEntry{Name: "../../escaped", Category: "skill", Type: "local", URL: source}
+
+	if _, err := RestoreModule(cfg, entry, source); err == nil {
+		t.Error("expected error for a name outside the repository")
+	}
+	if _, err := os.Stat(filepath.Join(base, "escaped")); !os.IsNotExist(err) {
+		t.Error("module should not be written outside the repository")
+	}
+}
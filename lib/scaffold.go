@@ -0,0 +1,225 @@
+package lib
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed templates
+var builtinTemplates embed.FS
+
+// TemplatesDirName 仓库中用户模板所在的目录（<repo>/templates/<skill|agent>/<name>/）
+const TemplatesDirName = "templates"
+
+// DefaultTemplate 未指定模板时使用的模板名
+const DefaultTemplate = "default"
+
+// templateSuffix 以此结尾的模板文件按 text/template 渲染并去掉后缀，其余文件原样复制
+const templateSuffix = ".tmpl"
+
+// NewModuleOptions sk new 的参数
+type NewModuleOptions struct {
+	Category    string // skill 或 agent
+	Name        string
+	Description string
+	Template    string     // 模板名，为空时使用 DefaultTemplate
+	Link        LinkConfig // 非空时写入 skillkit.toml
+	Scripts     bool       // 创建 scripts/ 目录
+	References  bool       // 创建 references/ 目录
+}
+
+// templateData 模板中可用的字段
+type templateData struct {
+	Name        string
+	Title       string // 由名称生成的标题，如 code-review → Code Review
+	Description string
+	Category    string
+}
+
+// ValidateModuleName 检查新模块名是否符合 Agent Skills 规范
+func ValidateModuleName(name string) error {
+	if utf8.RuneCountInString(name) > MaxNameLength {
+		return fmt.Errorf("name is longer than %d characters: %s", MaxNameLength, name)
+	}
+	if !skillNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid name %q: use lowercase letters, digits and single hyphens", name)
+	}
+	return nil
+}
+
+// ListTemplates 列出类别可用的模板名（内置和仓库中的用户模板，已排序去重）
+func ListTemplates(cfg *Config, category string) []string {
+	seen := make(map[string]bool)
+	if entries, err := fs.ReadDir(builtinTemplates, "templates/"+category); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() {
+				seen[entry.Name()] = true
+			}
+		}
+	}
+	if entries, err := os.ReadDir(filepath.Join(cfg.RepoPath, TemplatesDirName, category)); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() {
+				seen[entry.Name()] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadTemplate 查找模板，仓库中的用户模板优先于同名内置模板
+func loadTemplate(cfg *Config, category, name string) (fs.FS, error) {
+	dir := filepath.Join(cfg.RepoPath, TemplatesDirName, category, name)
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		return os.DirFS(dir), nil
+	}
+	if _, err := fs.Stat(builtinTemplates, "templates/"+category+"/"+name); err == nil {
+		return fs.Sub(builtinTemplates, "templates/"+category+"/"+name)
+	}
+	return nil, fmt.Errorf("template not found: %s (available: %s)", name, strings.Join(ListTemplates(cfg, category), ", "))
+}
+
+// CreateModule 按模板在仓库中创建新模块，失败时清理已创建的目录
+func CreateModule(cfg *Config, opts NewModuleOptions) (*Module, error) {
+	if opts.Category != "skill" && opts.Category != "agent" {
+		return nil, fmt.Errorf("unknown module type: %s (expected skill or agent)", opts.Category)
+	}
+	if err := ValidateModuleName(opts.Name); err != nil {
+		return nil, err
+	}
+	if opts.Template == "" {
+		opts.Template = DefaultTemplate
+	}
+	if opts.Description == "" {
+		opts.Description = fmt.Sprintf("Describe what %s does and when to use it.", opts.Name)
+	}
+
+	target := filepath.Join(cfg.RepoPath, opts.Category, opts.Name)
+	if _, err := os.Lstat(target); err == nil {
+		return nil, fmt.Errorf("%s '%s' already exists at %s", opts.Category, opts.Name, target)
+	}
+
+	tmpl, err := loadTemplate(cfg, opts.Category, opts.Template)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	data := templateData{
+		Name:        opts.Name,
+		Title:       moduleTitle(opts.Name),
+		Description: opts.Description,
+		Category:    opts.Category,
+	}
+	if err := renderTemplate(tmpl, target, data); err != nil {
+		os.RemoveAll(target)
+		return nil, err
+	}
+
+	if _, err := os.Stat(filepath.Join(target, SkillFileName(opts.Category))); err != nil {
+		os.RemoveAll(target)
+		return nil, fmt.Errorf("template %s does not provide %s", opts.Template, SkillFileName(opts.Category))
+	}
+
+	var dirs []string
+	if opts.Scripts {
+		dirs = append(dirs, "scripts")
+	}
+	if opts.References {
+		dirs = append(dirs, "references")
+	}
+	for _, dir := range dirs {
+		// 空目录无法提交到 git，放一个 .gitkeep
+		if err := os.MkdirAll(filepath.Join(target, dir), 0755); err != nil {
+			os.RemoveAll(target)
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(target, dir, ".gitkeep"), nil, 0644); err != nil {
+			os.RemoveAll(target)
+			return nil, err
+		}
+	}
+
+	if !opts.Link.IsEmpty() {
+		if err := SaveModuleConfig(target, &ModuleConfig{Link: opts.Link}); err != nil {
+			os.RemoveAll(target)
+			return nil, err
+		}
+	}
+
+	return loadModule(opts.Name, opts.Category, target)
+}
+
+// renderTemplate 把模板目录写入目标目录
+func renderTemplate(tmpl fs.FS, target string, data templateData) error {
+	funcs := template.FuncMap{"yaml": yamlScalar}
+	return fs.WalkDir(tmpl, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(target, filepath.FromSlash(strings.TrimSuffix(path, templateSuffix)))
+		if d.IsDir() {
+			return os.MkdirAll(dest, 0755)
+		}
+
+		content, err := fs.ReadFile(tmpl, path)
+		if err != nil {
+			return err
+		}
+		mode := os.FileMode(0644)
+		if info, err := d.Info(); err == nil && info.Mode()&0111 != 0 {
+			mode = 0755
+		}
+
+		if strings.HasSuffix(path, templateSuffix) {
+			t, err := template.New(path).Funcs(funcs).Option("missingkey=error").Parse(string(content))
+			if err != nil {
+				return fmt.Errorf("template %s: %w", path, err)
+			}
+			var buf bytes.Buffer
+			if err := t.Execute(&buf, data); err != nil {
+				return fmt.Errorf("template %s: %w", path, err)
+			}
+			content = buf.Bytes()
+		}
+		return os.WriteFile(dest, content, mode)
+	})
+}
+
+// yamlScalar 把字符串编码为 YAML 标量，必要时加引号，多行文本使用块标量
+func yamlScalar(s string) (string, error) {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+// moduleTitle 由模块名生成标题
+func moduleTitle(name string) string {
+	words := strings.Split(name, "-")
+	for i, w := range words {
+		if w != "" {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
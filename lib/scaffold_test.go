@@ -0,0 +1,110 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCreateModuleBuiltinTemplate(t *testing.T) {
+	cfg := &Config{RepoPath: t.TempDir()}
+
+	mod, err := CreateModule(cfg, NewModuleOptions{
+		Category:    "skill",
+		Name:        "pdf-tools",
+		Description: "Work with PDFs: extract text\nand fill forms.",
+		Link:        LinkConfig{Overrides: map[string]string{"claude": "pdf"}},
+		Scripts:     true,
+		References:  true,
+	})
+	if err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+	if mod.Path != filepath.Join(cfg.RepoPath, "skill", "pdf-tools") || mod.GetLinkName("claude") != "pdf" {
+		t.Errorf("unexpected module: %+v", mod)
+	}
+	if mod.Manifest == nil || mod.Manifest.Name != "pdf-tools" || mod.Description != "Work with PDFs: extract text\nand fill forms." {
+		t.Errorf("unexpected manifest: %+v", mod.Manifest)
+	}
+	for _, dir := range []string{"scripts", "references"} {
+		if _, err := os.Stat(filepath.Join(mod.Path, dir, ".gitkeep")); err != nil {
+			t.Errorf("expected %s/ to be created: %v", dir, err)
+		}
+	}
+	if result := LintModule(mod.Path); len(result.Issues) != 0 {
+		t.Errorf("generated skill has lint issues: %+v", result.Issues)
+	}
+
+	if _, err := CreateModule(cfg, NewModuleOptions{Category: "skill", Name: "pdf-tools"}); err == nil {
+		t.Error("expected error for existing module")
+	}
+}
+
+func TestCreateModuleAgent(t *testing.T) {
+	cfg := &Config{RepoPath: t.TempDir()}
+
+	mod, err := CreateModule(cfg, NewModuleOptions{Category: "agent", Name: "reviewer"})
+	if err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+	if mod.Category != "agent" || mod.Description == "" {
+		t.Errorf("unexpected module: %+v", mod)
+	}
+	if _, err := os.Stat(filepath.Join(mod.Path, "skillkit.toml")); !os.IsNotExist(err) {
+		t.Error("skillkit.toml should only be written when link names are set")
+	}
+	if result := LintModule(mod.Path); len(result.Issues) != 0 {
+		t.Errorf("generated agent has lint issues: %+v", result.Issues)
+	}
+}
+
+func TestCreateModuleUserTemplate(t *testing.T) {
+	cfg := &Config{RepoPath: t.TempDir()}
+	tmplDir := filepath.Join(cfg.RepoPath, TemplatesDirName, "skill", "team")
+	os.MkdirAll(filepath.Join(tmplDir, "scripts"), 0755)
+	os.WriteFile(filepath.Join(tmplDir, "SKILL.md.tmpl"), []byte("---\nname: {{.Name}}\ndescription: {{yaml .Description}}\n---\n# {{.Title}}\n"), 0644)
+	os.WriteFile(filepath.Join(tmplDir, "scripts", "run.sh"), []byte("echo {{.Name}}\n"), 0755)
+
+	if got := ListTemplates(cfg, "skill"); !reflect.DeepEqual(got, []string{"default", "team"}) {
+		t.Errorf("ListTemplates = %v", got)
+	}
+
+	mod, err := CreateModule(cfg, NewModuleOptions{Category: "skill", Name: "data-cleanup", Template: "team"})
+	if err != nil {
+		t.Fatalf("CreateModule failed: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(mod.Path, "SKILL.md"))
+	if string(data) != "---\nname: data-cleanup\ndescription: Describe what data-cleanup does and when to use it.\n---\n# Data Cleanup\n" {
+		t.Errorf("unexpected SKILL.md:\n%s", data)
+	}
+	// 非 .tmpl 文件原样复制并保留可执行权限
+	script := filepath.Join(mod.Path, "scripts", "run.sh")
+	data, _ = os.ReadFile(script)
+	info, err := os.Stat(script)
+	if err != nil || string(data) != "echo {{.Name}}\n" || info.Mode()&0100 == 0 {
+		t.Errorf("unexpected script %q (%v)", data, err)
+	}
+}
+
+func TestCreateModuleInvalid(t *testing.T) {
+	cfg := &Config{RepoPath: t.TempDir()}
+	tmplDir := filepath.Join(cfg.RepoPath, TemplatesDirName, "skill", "broken")
+	os.MkdirAll(tmplDir, 0755)
+	os.WriteFile(filepath.Join(tmplDir, "README.md"), []byte("no SKILL.md\n"), 0644)
+
+	tests := []NewModuleOptions{
+		{Category: "plugin", Name: "demo"},
+		{Category: "skill", Name: "Demo"},
+		{Category: "skill", Name: "demo", Template: "missing"},
+		{Category: "skill", Name: "demo", Template: "broken"},
+	}
+	for _, opts := range tests {
+		if _, err := CreateModule(cfg, opts); err == nil {
+			t.Errorf("expected error for %+v", opts)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(cfg.RepoPath, "skill", "demo")); !os.IsNotExist(err) {
+		t.Error("failed CreateModule should not leave a directory behind")
+	}
+}
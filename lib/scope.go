@@ -0,0 +1,188 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 链接作用域
+const (
+	ScopeGlobal  = "global"  // 平台全局目录，如 ~/.claude/
+	ScopeProject = "project" // 当前目录下的平台项目目录，如 ./.claude/
+)
+
+// AllScopes 全部作用域，全局在前
+var AllScopes = []string{ScopeGlobal, ScopeProject}
+
+// ParseScopes 从参数中取出作用域选项 (--global, --project, --all-scopes)
+// 返回作用域和剩余参数；未指定时返回 def
+func ParseScopes(args []string, def ...string) ([]string, []string) {
+	var scopes []string
+	var rest []string
+	for _, arg := range args {
+		switch arg {
+		case "--global":
+			scopes = []string{ScopeGlobal}
+		case "--project":
+			scopes = []string{ScopeProject}
+		case "--all-scopes":
+			scopes = AllScopes
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	if scopes == nil {
+		scopes = def
+	}
+	if len(scopes) == 0 {
+		scopes = []string{ScopeGlobal}
+	}
+	return scopes, rest
+}
+
+// orGlobal 未指定作用域时默认为全局
+func orGlobal(scopes []string) []string {
+	if len(scopes) == 0 {
+		return []string{ScopeGlobal}
+	}
+	return scopes
+}
+
+// GetBaseDir 返回平台在指定作用域的根目录，未配置时为空
+// 相对的项目目录按项目根目录解析
+func (p Platform) GetBaseDir(scope string) string {
+	if scope != ScopeProject {
+		return p.Global
+	}
+	if p.Project == "" || p.projectRoot == "" || filepath.IsAbs(p.Project) || p.Project[0] == '~' {
+		return p.Project
+	}
+	return filepath.Join(p.projectRoot, p.Project)
+}
+
+// GetTargetDir 返回平台在指定作用域下某类别的链接目录，作用域未配置时为空
+func (p Platform) GetTargetDir(scope, category string) string {
+	base := p.GetBaseDir(scope)
+	if base == "" {
+		return ""
+	}
+	return ResolvePath(base, p.GetCategoryDir(category))
+}
+
+// LinkName 返回模块在平台指定作用域下的链接名
+// 项目作用域优先使用项目清单中的链接名，其次为模块自身的链接名
+func (p Platform) LinkName(platKey string, mod *Module, scope string) string {
+	if scope == ScopeProject {
+		if name := p.projectLinks[filepath.Base(mod.Path)].LinkName(platKey); name != "" {
+			return name
+		}
+	}
+	return mod.GetLinkName(platKey)
+}
+
+// ModuleTarget 返回模块在平台指定作用域下的链接路径，作用域未配置时为空
+func ModuleTarget(p Platform, platKey string, mod *Module, scope string) string {
+	dir := p.GetTargetDir(scope, mod.Category)
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, p.LinkName(platKey, mod, scope))
+}
+
+// FindProjectRoot 从 start 向上查找项目根目录：最近的包含 .git、项目清单或平台项目目录（如 .claude/）的目录
+// 用户主目录中的平台目录是全局目录，查找到主目录或文件系统根时停止；未找到时返回空。
+// start 不在主目录下时（CI 工作区、/tmp、容器），上级目录中只认 .git 和项目清单，
+// 避免祖先目录中偶然存在的 .claude/ 等被当作项目根目录。
+func FindProjectRoot(start string, platforms map[string]Platform) string {
+	home, _ := os.UserHomeDir()
+	var platformDirs []string
+	for _, p := range platforms {
+		if p.Project != "" && !filepath.IsAbs(p.Project) && p.Project[0] != '~' {
+			platformDirs = append(platformDirs, p.Project)
+		}
+	}
+
+	start = filepath.Clean(start)
+	inHome := home != "" && isWithin(start, home)
+	for dir := start; dir != home; {
+		markers := []string{".git", ManifestName}
+		if inHome || dir == start {
+			markers = append(markers, platformDirs...)
+		}
+		for _, marker := range markers {
+			if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+				return dir
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return ""
+}
+
+// SetProjectRoot 设置项目根目录，平台的项目目录相对它解析
+// 项目清单中的链接名用于该项目中的链接（清单无法读取时忽略，由 sk install 报告）
+func (cfg *Config) SetProjectRoot(root string) {
+	cfg.ProjectRoot = root
+	var links map[string]*LinkConfig
+	if root != "" {
+		if m, err := LoadManifest(filepath.Join(root, ManifestName)); err == nil {
+			links = m.LinkNames()
+		}
+	}
+	for key, p := range cfg.Platforms {
+		p.projectRoot = root
+		p.projectLinks = links
+		cfg.Platforms[key] = p
+	}
+}
+
+// InProject 检查是否在项目中运行（找到了项目根目录或通过 --root 指定）
+func (cfg *Config) InProject() bool {
+	return cfg.ProjectRoot != ""
+}
+
+// ProjectDir 返回项目作用域实际使用的目录：项目根目录，不在项目中时为当前目录
+func (cfg *Config) ProjectDir() string {
+	if cfg.ProjectRoot != "" {
+		return cfg.ProjectRoot
+	}
+	cwd, _ := os.Getwd()
+	return cwd
+}
+
+// ParseRootFlag 从参数中取出全局选项 --root <dir> / --root=<dir>
+// 返回目录（未指定时为空）和剩余参数
+func ParseRootFlag(args []string) (string, []string, error) {
+	root := ""
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--root":
+			if i+1 >= len(args) {
+				return "", nil, fmt.Errorf("--root requires a directory")
+			}
+			root = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--root="):
+			root = strings.TrimPrefix(arg, "--root=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	if root == "" {
+		return "", rest, nil
+	}
+
+	root = ResolvePath(root)
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return "", nil, fmt.Errorf("project root is not a directory: %s", root)
+	}
+	return root, rest, nil
+}
@@ -0,0 +1,188 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseScopes(t *testing.T) {
+	tests := []struct {
+		args     []string
+		def      []string
+		expected []string
+		rest     int
+	}{
+		{[]string{"foo"}, nil, []string{ScopeGlobal}, 1},
+		{[]string{"foo", "--project"}, nil, []string{ScopeProject}, 1},
+		{[]string{"--all-scopes", "foo", "claude"}, nil, AllScopes, 2},
+		{[]string{"--project", "--global"}, nil, []string{ScopeGlobal}, 0},
+		{nil, AllScopes, AllScopes, 0},
+	}
+	for _, tt := range tests {
+		scopes, rest := ParseScopes(tt.args, tt.def...)
+		if len(scopes) != len(tt.expected) || scopes[0] != tt.expected[0] || len(rest) != tt.rest {
+			t.Errorf("ParseScopes(%v): got %v %v, expected %v", tt.args, scopes, rest, tt.expected)
+		}
+	}
+}
+
+func TestProjectScopeLinks(t *testing.T) {
+	tmp := t.TempDir()
+	project := filepath.Join(tmp, "project")
+	os.MkdirAll(project, 0755)
+	t.Chdir(project)
+
+	cfg := &Config{
+		RepoPath: filepath.Join(tmp, "repo"),
+		Platforms: map[string]Platform{
+			"claude": {Global: filepath.Join(tmp, "home", ".claude"), Project: ".claude/", SkillDir: "skills"},
+			"goose":  {Global: filepath.Join(tmp, "home", ".goose"), SkillDir: "skills"}, // 无项目目录
+		},
+	}
+	mod := &Module{Name: "foo", Category: "skill", Path: filepath.Join(cfg.RepoPath, "skill", "foo")}
+	os.MkdirAll(mod.Path, 0755)
+
+	if target := ModuleTarget(cfg.Platforms["goose"], "goose", mod, ScopeProject); target != "" {
+		t.Errorf("expected no project target for goose, got %s", target)
+	}
+
+	plan := PlanLinks([]*Module{mod}, cfg.Platforms, ScopeProject)
+	if len(plan.Ops) != 1 || plan.Ops[0].Target != filepath.Join(project, ".claude", "skills", "foo") {
+		t.Fatalf("unexpected project plan: %+v", plan.Ops)
+	}
+	ApplyPlan(plan, false)
+
+	// 只链接到项目时，全局作用域看不到
+	if keys := GetSyncedPlatformKeys(cfg, mod); len(keys) != 0 {
+		t.Errorf("expected no global links, got %v", keys)
+	}
+	if keys := GetSyncedPlatformKeys(cfg, mod, AllScopes...); len(keys) != 1 || keys[0] != "claude" {
+		t.Errorf("expected claude linked in project, got %v", keys)
+	}
+
+	states := ModuleLinkStates(cfg, mod, AllScopes...)
+	if len(states) != 3 {
+		t.Fatalf("expected 2 global and 1 project states, got %+v", states)
+	}
+	last := states[2]
+	if last.Scope != ScopeProject || last.Platform != "claude" || last.State != LinkStateOK {
+		t.Errorf("unexpected project state: %+v", last)
+	}
+}
+
+func TestFindProjectRoot(t *testing.T) {
+	tmp := t.TempDir()
+	home := filepath.Join(tmp, "home")
+	t.Setenv("HOME", home)
+	platforms := map[string]Platform{"claude": {Project: ".claude/", Global: "~/.claude/"}}
+
+	mkdir := func(parts ...string) string {
+		dir := filepath.Join(append([]string{tmp}, parts...)...)
+		os.MkdirAll(dir, 0755)
+		return dir
+	}
+
+	repo := mkdir("home", "work", "app")
+	mkdir("home", "work", "app", ".git")
+	sub := mkdir("home", "work", "app", "src", "pkg")
+	if root := FindProjectRoot(sub, platforms); root != repo {
+		t.Errorf("expected .git root %s, got %s", repo, root)
+	}
+
+	// 没有 .git 时，最近的平台项目目录或清单也算项目根目录
+	plain := mkdir("home", "notes")
+	mkdir("home", "notes", ".claude")
+	if root := FindProjectRoot(mkdir("home", "notes", "daily"), platforms); root != plain {
+		t.Errorf("expected platform dir root %s, got %s", plain, root)
+	}
+	withManifest := mkdir("home", "docs")
+	os.WriteFile(filepath.Join(withManifest, ManifestName), nil, 0644)
+	if root := FindProjectRoot(withManifest, platforms); root != withManifest {
+		t.Errorf("expected manifest root %s, got %s", withManifest, root)
+	}
+
+	// 主目录中的 .claude 是全局目录，不作为项目根目录
+	mkdir("home", ".claude")
+	if root := FindProjectRoot(mkdir("home", "scratch"), platforms); root != "" {
+		t.Errorf("expected no project root, got %s", root)
+	}
+
+	// 主目录之外，上级目录中的平台目录不算项目根目录，.git 仍然算
+	mkdir("ci", ".claude")
+	workspace := mkdir("ci", "workspace", "job")
+	if root := FindProjectRoot(workspace, platforms); root != "" {
+		t.Errorf("expected no project root outside home, got %s", root)
+	}
+	mkdir("ci", "workspace", ".claude")
+	if root := FindProjectRoot(filepath.Join(tmp, "ci", "workspace"), platforms); root != filepath.Join(tmp, "ci", "workspace") {
+		t.Errorf("expected platform dir in the start directory to count, got %s", root)
+	}
+	mkdir("ci", "workspace", "job", ".git")
+	if root := FindProjectRoot(mkdir("ci", "workspace", "job", "src"), platforms); root != workspace {
+		t.Errorf("expected .git root %s outside home, got %s", workspace, root)
+	}
+}
+
+func TestProjectRootBaseDir(t *testing.T) {
+	cfg := &Config{Platforms: map[string]Platform{
+		"claude": {Project: ".claude/", Global: "~/.claude/", SkillDir: "skills"},
+		"abs":    {Project: "/opt/shared/", SkillDir: "skills"},
+	}}
+	cfg.SetProjectRoot("/work/app")
+
+	if dir := cfg.Platforms["claude"].GetTargetDir(ScopeProject, "skill"); dir != "/work/app/.claude/skills" {
+		t.Errorf("expected project dir under root, got %s", dir)
+	}
+	if dir := cfg.Platforms["abs"].GetTargetDir(ScopeProject, "skill"); dir != "/opt/shared/skills" {
+		t.Errorf("expected absolute project dir unchanged, got %s", dir)
+	}
+	if !cfg.InProject() || cfg.ProjectDir() != "/work/app" {
+		t.Errorf("unexpected project root: %s", cfg.ProjectDir())
+	}
+}
+
+func TestProjectManifestLinkNames(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, ManifestName), []byte(`[[module]]
+name = "code-review"
+
+[module.link]
+default = "review"
+
+[module.link.overrides]
+cursor = "cr"
+`), 0644)
+
+	cfg := &Config{Platforms: map[string]Platform{
+		"claude": {Project: ".claude/", Global: "/home/u/.claude/", SkillDir: "skills"},
+		"cursor": {Project: ".cursor/", Global: "/home/u/.cursor/", SkillDir: "skills"},
+	}}
+	cfg.SetProjectRoot(root)
+
+	// 模块自身的 [link].default 替换了 Name，清单仍按目录名匹配
+	mod := &Module{Name: "global-alias", Category: "skill", Path: "/pool/skill/code-review", Aliases: map[string]string{}}
+	tests := []struct {
+		platform, scope, want string
+	}{
+		{"claude", ScopeProject, filepath.Join(root, ".claude/skills/review")},
+		{"cursor", ScopeProject, filepath.Join(root, ".cursor/skills/cr")},
+		{"claude", ScopeGlobal, "/home/u/.claude/skills/global-alias"},
+	}
+	for _, tt := range tests {
+		if got := ModuleTarget(cfg.Platforms[tt.platform], tt.platform, mod, tt.scope); got != tt.want {
+			t.Errorf("ModuleTarget(%s, %s) = %s, expected %s", tt.platform, tt.scope, got, tt.want)
+		}
+	}
+}
+
+func TestParseRootFlag(t *testing.T) {
+	tmp := t.TempDir()
+	root, rest, err := ParseRootFlag([]string{"use", "foo", "--root", tmp, "--project"})
+	if err != nil || root != tmp || len(rest) != 3 {
+		t.Errorf("unexpected result: %s %v %v", root, rest, err)
+	}
+	if _, _, err := ParseRootFlag([]string{"--root=" + filepath.Join(tmp, "missing")}); err == nil {
+		t.Error("expected error for missing root directory")
+	}
+}
@@ -0,0 +1,302 @@
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// IndexFormatVersion 索引文件格式版本
+const IndexFormatVersion = 1
+
+// DefaultIndexTTL 远程索引缓存的默认有效期
+const DefaultIndexTTL = 24 * time.Hour
+
+// indexFetchTimeout 下载远程索引的超时
+const indexFetchTimeout = 30 * time.Second
+
+// MaxIndexDownloadSize 远程索引文件的大小上限
+const MaxIndexDownloadSize = 16 << 20
+
+// indexNamePattern 索引名用作缓存文件名，只允许字母、数字、点、下划线和连字符
+var indexNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// IndexConfig 技能索引配置（platforms.toml 中的 [[indexes]]）
+type IndexConfig struct {
+	Name string `toml:"name"`
+	URL  string `toml:"url"`           // http(s):// 地址、file:// 地址或本地路径
+	TTL  string `toml:"ttl,omitempty"` // 远程索引的缓存有效期，如 "1h"，默认 24h
+}
+
+// SkillIndex 技能索引文件
+type SkillIndex struct {
+	Version int          `json:"version"`
+	Skills  []IndexEntry `json:"skills"`
+}
+
+// IndexEntry 索引中的单个模块
+type IndexEntry struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Category    string   `json:"category,omitempty"` // skill（默认）或 agent
+	Tags        []string `json:"tags,omitempty"`
+	Version     string   `json:"version,omitempty"`
+	Source      string   `json:"source"`           // sk add 接受的来源，如 owner/repo/path@v1.0.0
+	Commit      string   `json:"commit,omitempty"` // 来源解析到的提交（sk registry build 从锁文件读取）
+	Hash        string   `json:"hash,omitempty"`   // 模块目录内容哈希
+}
+
+// SearchResult 搜索结果
+type SearchResult struct {
+	IndexEntry
+	Index string `json:"index"` // 结果所在索引的名称
+	score int
+}
+
+// SearchReport sk search 的输出
+type SearchReport struct {
+	SchemaVersion int            `json:"schema_version"`
+	Query         string         `json:"query"`
+	Results       []SearchResult `json:"results"`
+	Warnings      []string       `json:"warnings,omitempty"` // 不可用或使用过期缓存的索引
+}
+
+// IndexCachePath 返回远程索引的缓存文件路径
+func IndexCachePath(cfg *Config, name string) string {
+	return filepath.Join(cfg.RepoPath, ".cache", "index", name+".json")
+}
+
+// isRemoteIndex 检查索引地址是否需要通过 HTTP 获取
+func isRemoteIndex(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
+
+// validateIndexName 检查索引名能否安全地用作缓存文件名
+func validateIndexName(name string) error {
+	if !indexNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid index name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// FindIndexes 返回要搜索的索引，names 为空时返回全部
+func FindIndexes(cfg *Config, names []string) ([]IndexConfig, error) {
+	for _, ic := range cfg.Indexes {
+		if err := validateIndexName(ic.Name); err != nil {
+			return nil, err
+		}
+	}
+	if len(names) == 0 {
+		return cfg.Indexes, nil
+	}
+	var result []IndexConfig
+	for _, name := range names {
+		found := false
+		for _, ic := range cfg.Indexes {
+			if ic.Name == name {
+				result = append(result, ic)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown index: %s", name)
+		}
+	}
+	return result, nil
+}
+
+// LoadIndex 读取索引
+// 远程索引在缓存有效期内直接使用缓存，refresh 时强制重新下载；下载失败或离线时使用过期缓存并返回 stale=true
+// 下载成功但写入缓存失败时仍返回索引，同时返回该错误
+func LoadIndex(cfg *Config, ic IndexConfig, refresh bool) (index *SkillIndex, stale bool, err error) {
+	if ic.Name == "" || ic.URL == "" {
+		return nil, false, fmt.Errorf("index must have a name and a url")
+	}
+	if err := validateIndexName(ic.Name); err != nil {
+		return nil, false, err
+	}
+
+	if !isRemoteIndex(ic.URL) {
+		data, err := os.ReadFile(ResolvePath(strings.TrimPrefix(ic.URL, "file://")))
+		if err != nil {
+			return nil, false, err
+		}
+		index, err := ParseIndex(data)
+		return index, false, err
+	}
+
+	ttl := DefaultIndexTTL
+	if ic.TTL != "" {
+		if ttl, err = time.ParseDuration(ic.TTL); err != nil {
+			return nil, false, fmt.Errorf("index %s: invalid ttl %q", ic.Name, ic.TTL)
+		}
+	}
+
+	cachePath := IndexCachePath(cfg, ic.Name)
+	info, cacheErr := os.Stat(cachePath)
+	fresh := cacheErr == nil && time.Since(info.ModTime()) < ttl
+	if !cfg.Offline && (refresh || !fresh) {
+		data, err := fetchIndex(cfg, ic.URL)
+		if err == nil {
+			if index, err = ParseIndex(data); err != nil {
+				return nil, false, fmt.Errorf("%s: %w", Redact(ic.URL), err)
+			}
+			if err := writeIndexCache(cachePath, data); err != nil {
+				return index, false, fmt.Errorf("failed to cache index %s: %w", ic.Name, err)
+			}
+			return index, false, nil
+		}
+		if cacheErr != nil {
+			return nil, false, err
+		}
+		fresh = false
+	}
+
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		if cfg.Offline && os.IsNotExist(err) {
+			return nil, false, fmt.Errorf("index %s is not cached (offline)", ic.Name)
+		}
+		return nil, false, err
+	}
+	index, err = ParseIndex(data)
+	return index, !fresh, err
+}
+
+// fetchIndex 通过 HTTP 下载索引（按主机附加凭据），超过 MaxIndexDownloadSize 时失败
+func fetchIndex(cfg *Config, url string) ([]byte, error) {
+	resp, err := httpGet(cfg, url, indexFetchTimeout, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", Redact(url), resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxIndexDownloadSize+1))
+	if err == nil && int64(len(data)) > MaxIndexDownloadSize {
+		err = fmt.Errorf("index is larger than %s: %s", FormatSize(MaxIndexDownloadSize), Redact(url))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// writeIndexCache 原子地写入索引缓存
+func writeIndexCache(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ParseIndex 解析并校验索引文件
+func ParseIndex(data []byte) (*SkillIndex, error) {
+	var index SkillIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("invalid index: %w", err)
+	}
+	if index.Version > IndexFormatVersion {
+		return nil, fmt.Errorf("unsupported index version %d (max %d)", index.Version, IndexFormatVersion)
+	}
+	for i, entry := range index.Skills {
+		if entry.Name == "" || entry.Source == "" {
+			return nil, fmt.Errorf("invalid index: entry %d needs a name and a source", i+1)
+		}
+		if err := ValidateDirName(entry.Name); err != nil {
+			return nil, fmt.Errorf("invalid index: entry %d: %w", i+1, err)
+		}
+		if entry.Category == "" {
+			index.Skills[i].Category = "skill"
+		} else if err := ValidateCategory(entry.Category); err != nil {
+			return nil, fmt.Errorf("invalid index: entry %d: %w", i+1, err)
+		}
+	}
+	return &index, nil
+}
+
+// SearchIndex 在索引中搜索，每个查询词都须出现在名称、标签或描述中；空查询返回全部
+func SearchIndex(index *SkillIndex, indexName, query string) []SearchResult {
+	terms := strings.Fields(strings.ToLower(query))
+
+	var results []SearchResult
+	for _, entry := range index.Skills {
+		score, ok := matchEntry(entry, terms)
+		if ok {
+			results = append(results, SearchResult{IndexEntry: entry, Index: indexName, score: score})
+		}
+	}
+	return results
+}
+
+// matchEntry 计算条目与查询词的匹配分数：名称 > 标签 > 描述
+func matchEntry(entry IndexEntry, terms []string) (int, bool) {
+	name := strings.ToLower(entry.Name)
+	desc := strings.ToLower(entry.Description)
+
+	score := 0
+	for _, term := range terms {
+		termScore := 0
+		switch {
+		case name == term:
+			termScore = 10
+		case strings.Contains(name, term):
+			termScore = 5
+		}
+		for _, tag := range entry.Tags {
+			if strings.ToLower(tag) == term && termScore < 3 {
+				termScore = 3
+			}
+		}
+		if termScore == 0 && strings.Contains(desc, term) {
+			termScore = 1
+		}
+		if termScore == 0 {
+			return 0, false
+		}
+		score += termScore
+	}
+	return score, true
+}
+
+// SortSearchResults 按匹配分数、名称排序，并去掉来源相同的重复结果（保留先出现的索引）
+func SortSearchResults(results []SearchResult) []SearchResult {
+	seen := make(map[string]bool)
+	unique := results[:0]
+	for _, r := range results {
+		key := r.Category + ":" + r.Name + ":" + r.Source
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, r)
+		}
+	}
+	sort.SliceStable(unique, func(i, j int) bool {
+		if unique[i].score != unique[j].score {
+			return unique[i].score > unique[j].score
+		}
+		return unique[i].Name < unique[j].Name
+	})
+	return unique
+}
+
+// DiscoveredSkill 转换为安装选择器使用的技能
+func (r SearchResult) DiscoveredSkill() *DiscoveredSkill {
+	return &DiscoveredSkill{
+		Name:        r.Name,
+		Description: r.Description,
+		Category:    r.Category,
+	}
+}
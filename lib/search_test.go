@@ -0,0 +1,212 @@
+package lib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testIndex = `{
+  "version": 1,
+  "skills": [
+    {"name": "pdf-tools", "description": "Extract text and fill PDF forms", "tags": ["pdf", "documents"], "source": "acme/skills/pdf-tools"},
+    {"name": "csv-tools", "description": "Clean CSV files and export to pdf", "tags": ["data"], "source": "acme/skills/csv-tools"},
+    {"name": "reviewer", "category": "agent", "description": "Reviews pull requests", "source": "acme/agents@v1.0.0"}
+  ]
+}`
+
+func TestParseIndex(t *testing.T) {
+	index, err := ParseIndex([]byte(testIndex))
+	if err != nil {
+		t.Fatalf("ParseIndex failed: %v", err)
+	}
+	if len(index.Skills) != 3 || index.Skills[0].Category != "skill" || index.Skills[2].Category != "agent" {
+		t.Errorf("unexpected index: %+v", index)
+	}
+
+	for _, data := range []string{
+		`not json`,
+		`{"version": 2, "skills": []}`,
+		`{"skills": [{"name": "x"}]}`,
+		`{"skills": [{"name": "../../x", "source": "o/r"}]}`,
+		`{"skills": [{"name": "a/b", "source": "o/r"}]}`,
+		`{"skills": [{"name": "x", "source": "o/r", "category": "../skill"}]}`,
+	} {
+		if _, err := ParseIndex([]byte(data)); err == nil {
+			t.Errorf("expected error for %s", data)
+		}
+	}
+}
+
+func TestSearchIndex(t *testing.T) {
+	index, _ := ParseIndex([]byte(testIndex))
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"pdf", []string{"pdf-tools", "csv-tools"}},
+		{"PDF documents", []string{"pdf-tools"}},
+		{"data", []string{"csv-tools"}},
+		{"review", []string{"reviewer"}},
+		{"pdf missing", nil},
+		{"", []string{"csv-tools", "pdf-tools", "reviewer"}},
+	}
+	for _, tt := range tests {
+		results := SortSearchResults(SearchIndex(index, "main", tt.query))
+		var names []string
+		for _, r := range results {
+			names = append(names, r.Name)
+			if r.Index != "main" {
+				t.Errorf("%q: result without index name: %+v", tt.query, r)
+			}
+		}
+		if len(names) != len(tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.query, names, tt.want)
+			continue
+		}
+		for i := range names {
+			if names[i] != tt.want[i] {
+				t.Errorf("%q: got %v, want %v", tt.query, names, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestSortSearchResultsDedup(t *testing.T) {
+	index, _ := ParseIndex([]byte(testIndex))
+	results := append(SearchIndex(index, "first", "pdf-tools"), SearchIndex(index, "second", "pdf-tools")...)
+	results = SortSearchResults(results)
+	if len(results) != 1 || results[0].Index != "first" {
+		t.Errorf("expected one result from the first index, got %+v", results)
+	}
+
+	skill := results[0].DiscoveredSkill()
+	if skill.Name != "pdf-tools" || skill.Category != "skill" || skill.Description == "" {
+		t.Errorf("unexpected skill: %+v", skill)
+	}
+}
+
+func TestLoadIndexLocal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.json")
+	os.WriteFile(path, []byte(testIndex), 0644)
+	cfg := &Config{RepoPath: t.TempDir()}
+
+	for _, url := range []string{path, "file://" + path} {
+		index, stale, err := LoadIndex(cfg, IndexConfig{Name: "local", URL: url}, false)
+		if err != nil || stale || len(index.Skills) != 3 {
+			t.Errorf("LoadIndex(%s) = %v, %v, %v", url, index, stale, err)
+		}
+	}
+	if _, err := os.Stat(IndexCachePath(cfg, "local")); !os.IsNotExist(err) {
+		t.Error("local indexes should not be cached")
+	}
+	if _, _, err := LoadIndex(cfg, IndexConfig{URL: path}, false); err == nil {
+		t.Error("expected error for index without name")
+	}
+}
+
+func TestLoadIndexRemoteCache(t *testing.T) {
+	requests := 0
+	fail := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if fail {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(testIndex))
+	}))
+	defer server.Close()
+
+	cfg := &Config{RepoPath: t.TempDir()}
+	ic := IndexConfig{Name: "remote", URL: server.URL + "/skills.json"}
+
+	if _, stale, err := LoadIndex(cfg, ic, false); err != nil || stale || requests != 1 {
+		t.Fatalf("first load: stale=%v err=%v requests=%d", stale, err, requests)
+	}
+	// 缓存有效期内不再请求
+	if _, stale, err := LoadIndex(cfg, ic, false); err != nil || stale || requests != 1 {
+		t.Fatalf("cached load: stale=%v err=%v requests=%d", stale, err, requests)
+	}
+	// --refresh 强制请求，失败时使用过期缓存
+	fail = true
+	index, stale, err := LoadIndex(cfg, ic, true)
+	if err != nil || !stale || requests != 2 || len(index.Skills) != 3 {
+		t.Fatalf("refresh with failing server: stale=%v err=%v requests=%d", stale, err, requests)
+	}
+
+	// 缓存过期后重新请求
+	old := time.Now().Add(-2 * DefaultIndexTTL)
+	os.Chtimes(IndexCachePath(cfg, "remote"), old, old)
+	fail = false
+	if _, stale, err := LoadIndex(cfg, ic, false); err != nil || stale || requests != 3 {
+		t.Fatalf("expired load: stale=%v err=%v requests=%d", stale, err, requests)
+	}
+
+	// 离线时只读缓存
+	cfg.Offline = true
+	if _, _, err := LoadIndex(cfg, ic, true); err != nil || requests != 3 {
+		t.Fatalf("offline load: err=%v requests=%d", err, requests)
+	}
+	if _, _, err := LoadIndex(cfg, IndexConfig{Name: "other", URL: server.URL}, false); err == nil {
+		t.Error("expected error for uncached index in offline mode")
+	}
+}
+
+func TestFindIndexes(t *testing.T) {
+	cfg := &Config{Indexes: []IndexConfig{{Name: "a", URL: "a.json"}, {Name: "b", URL: "b.json"}}}
+	if got, _ := FindIndexes(cfg, nil); len(got) != 2 {
+		t.Errorf("expected all indexes, got %v", got)
+	}
+	if got, err := FindIndexes(cfg, []string{"b"}); err != nil || len(got) != 1 || got[0].Name != "b" {
+		t.Errorf("FindIndexes(b) = %v, %v", got, err)
+	}
+	if _, err := FindIndexes(cfg, []string{"c"}); err == nil {
+		t.Error("expected error for unknown index")
+	}
+
+	cfg.Indexes = append(cfg.Indexes, IndexConfig{Name: "../escape", URL: "c.json"})
+	if _, err := FindIndexes(cfg, nil); err == nil {
+		t.Error("expected error for an index name with a path separator")
+	}
+	if _, _, err := LoadIndex(&Config{RepoPath: t.TempDir()}, IndexConfig{Name: "../escape", URL: "https://example.com/index.json"}, false); err == nil {
+		t.Error("expected LoadIndex to reject an unsafe index name")
+	}
+}
+
+func TestLoadIndexCacheWriteFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testIndex))
+	}))
+	defer server.Close()
+
+	// 缓存目录被普通文件占用，写入缓存失败
+	cfg := &Config{RepoPath: t.TempDir()}
+	os.MkdirAll(filepath.Join(cfg.RepoPath, ".cache"), 0755)
+	os.WriteFile(filepath.Join(cfg.RepoPath, ".cache", "index"), nil, 0644)
+
+	index, stale, err := LoadIndex(cfg, IndexConfig{Name: "remote", URL: server.URL}, false)
+	if index == nil || len(index.Skills) != 3 || stale {
+		t.Fatalf("expected the downloaded index, got %v, stale=%v", index, stale)
+	}
+	if err == nil {
+		t.Error("expected the cache write error to be reported")
+	}
+}
+
+func TestFetchIndexSizeLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, MaxIndexDownloadSize+1))
+	}))
+	defer server.Close()
+
+	if _, err := fetchIndex(nil, server.URL); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("expected size limit error, got %v", err)
+	}
+}
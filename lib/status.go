@@ -0,0 +1,352 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ReportSchemaVersion 结构化输出的 schema 版本，字段发生不兼容变化时递增
+const ReportSchemaVersion = 1
+
+// 链接状态
+const (
+	LinkStateOK       = "ok"       // 软链接指向模块，或副本与源一致
+	LinkStateBroken   = "broken"   // 软链接指向其他位置，或副本来自其他源
+	LinkStateBlocked  = "blocked"  // 目标被非托管的实体文件/目录占用
+	LinkStateOutdated = "outdated" // 副本的源已变更
+	LinkStateModified = "modified" // 副本被本地修改
+	LinkStateMissing  = "missing"  // 未分发
+)
+
+// LinkState 模块在某个平台上的分发状态
+type LinkState struct {
+	Platform string `json:"platform"`
+	Scope    string `json:"scope"`
+	Target   string `json:"target"`
+	State    string `json:"state"`
+	Mode     string `json:"mode,omitempty"`      // 实际的分发方式: symlink | relative | copy | hardlink
+	PointsTo string `json:"points_to,omitempty"` // 软链接的指向或副本记录的源
+}
+
+// InspectLink 检查目标路径相对于模块源的分发状态
+// 相对软链接按链接所在目录解析后再与源比较
+func InspectLink(source, target string) LinkState {
+	ls := LinkState{Target: target, State: LinkStateMissing}
+
+	if IsSymlink(target) {
+		ls.Mode = LinkModeSymlink
+		if raw, err := ReadSymlink(target); err == nil && !filepath.IsAbs(raw) {
+			ls.Mode = LinkModeRelative
+		}
+		dest, _ := resolveLinkDest(target)
+		ls.PointsTo = dest
+		ls.State = LinkStateBroken
+		if dest == filepath.Clean(source) {
+			ls.State = LinkStateOK
+		}
+		return ls
+	}
+
+	if marker, err := ReadCopyMarker(target); err == nil {
+		ls.Mode = marker.Mode
+		ls.PointsTo = marker.Source
+		state, err := CopyState(source, target)
+		switch {
+		case err != nil || state == CopyStateForeign:
+			ls.State = LinkStateBroken
+		case state == CopyStateModified:
+			ls.State = LinkStateModified
+		case state == CopyStateOutdated:
+			ls.State = LinkStateOutdated
+		default:
+			ls.State = LinkStateOK
+		}
+		return ls
+	}
+
+	if marker, err := ReadVendorMarker(target); err == nil {
+		ls.Mode = LinkModeVendor
+		ls.State = LinkStateBroken
+		if marker.Module == filepath.Base(source) {
+			state, err := VendorState(source, target)
+			switch {
+			case err != nil:
+			case state == CopyStateModified:
+				ls.State = LinkStateModified
+			case state == CopyStateOutdated:
+				ls.State = LinkStateOutdated
+			default:
+				ls.State = LinkStateOK
+			}
+		}
+		return ls
+	}
+
+	if _, err := os.Stat(target); err == nil {
+		ls.State = LinkStateBlocked
+	}
+	return ls
+}
+
+// ModuleLinkStates 返回模块在各平台的分发状态（按作用域、平台 key 排序，默认只看全局）
+// 未配置项目目录的平台不会出现在项目作用域中
+func ModuleLinkStates(cfg *Config, mod *Module, scopes ...string) []LinkState {
+	keys := make([]string, 0, len(cfg.Platforms))
+	for key := range cfg.Platforms {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	states := make([]LinkState, 0, len(keys))
+	for _, scope := range orGlobal(scopes) {
+		for _, key := range keys {
+			target := ModuleTarget(cfg.Platforms[key], key, mod, scope)
+			if target == "" {
+				continue
+			}
+			ls := InspectLink(mod.Path, target)
+			ls.Platform = key
+			ls.Scope = scope
+			states = append(states, ls)
+		}
+	}
+	return states
+}
+
+// ModuleReport 模块及其分发状态
+type ModuleReport struct {
+	Name        string            `json:"name"`
+	Category    string            `json:"category"`
+	Path        string            `json:"path"`
+	Description string            `json:"description,omitempty"`
+	Aliases     map[string]string `json:"aliases,omitempty"`
+	Manifest    *SkillManifest    `json:"manifest,omitempty"` // SKILL.md/AGENT.md 的 frontmatter
+	Links       []LinkState       `json:"links"`
+}
+
+// NewModuleReport 生成模块报告
+func NewModuleReport(cfg *Config, mod *Module, scopes ...string) ModuleReport {
+	return ModuleReport{
+		Name:        mod.Name,
+		Category:    mod.Category,
+		Path:        mod.Path,
+		Description: mod.Description,
+		Aliases:     mod.Aliases,
+		Manifest:    mod.Manifest,
+		Links:       ModuleLinkStates(cfg, mod, scopes...),
+	}
+}
+
+// ListReport sk list 的输出
+type ListReport struct {
+	SchemaVersion int            `json:"schema_version"`
+	Modules       []ModuleReport `json:"modules"`
+}
+
+// StatusSummary 健康检查统计
+type StatusSummary struct {
+	Healthy        int `json:"healthy"`
+	Broken         int `json:"broken"`  // broken + blocked
+	Drifted        int `json:"drifted"` // outdated + modified
+	Missing        int `json:"missing"`
+	MissingDefault int `json:"missing_default"` // missing 中属于默认平台的部分
+}
+
+// Add 计入一个链接状态
+func (s *StatusSummary) Add(state string) {
+	switch state {
+	case LinkStateOK:
+		s.Healthy++
+	case LinkStateBroken, LinkStateBlocked:
+		s.Broken++
+	case LinkStateOutdated, LinkStateModified:
+		s.Drifted++
+	default:
+		s.Missing++
+	}
+}
+
+// StatusReport sk status 的输出
+type StatusReport struct {
+	SchemaVersion int            `json:"schema_version"`
+	Summary       StatusSummary  `json:"summary"`
+	Modules       []ModuleReport `json:"modules"`
+	Orphans       []LinkIssue    `json:"orphans"` // 不属于任何模块的遗留链接
+}
+
+// sk status --check 的退出码，多类问题同时存在时按位组合
+const (
+	ExitError          = 1  // 配置加载、遗留链接扫描失败等一般错误
+	ExitBroken         = 2  // 软链接指向错误位置，或副本来自其他源
+	ExitBlocked        = 4  // 目标被实体文件/目录占用
+	ExitMissingDefault = 8  // 默认平台上缺少链接
+	ExitDrifted        = 16 // 副本已过期或被本地修改
+)
+
+// NewStatusReport 检查所有模块在指定作用域（默认全局）的分发状态和遗留链接
+// 缺失的默认链接见 requiredLinks。
+// 遗留链接扫描失败（如平台目录不可读）时仍返回其余结果，同时返回该错误。
+func NewStatusReport(cfg *Config, modules []*Module, scopes ...string) (StatusReport, error) {
+	scopes = orGlobal(scopes)
+
+	required := make(map[string]requiredSet)
+	for _, scope := range scopes {
+		required[scope] = requiredLinks(cfg, scope)
+	}
+
+	report := StatusReport{SchemaVersion: ReportSchemaVersion, Modules: []ModuleReport{}}
+	for _, mod := range modules {
+		mr := NewModuleReport(cfg, mod, scopes...)
+		for _, ls := range mr.Links {
+			report.Summary.Add(ls.State)
+			if ls.State == LinkStateMissing && required[ls.Scope].requires(mod, ls.Platform) {
+				report.Summary.MissingDefault++
+			}
+		}
+		report.Modules = append(report.Modules, mr)
+	}
+
+	report.Orphans = []LinkIssue{}
+	orphans, err := ScanOrphans(cfg, modules, scopes...)
+	if err != nil {
+		return report, fmt.Errorf("failed to scan for orphaned links: %w", err)
+	}
+	if orphans != nil {
+		report.Orphans = orphans
+	}
+	return report, nil
+}
+
+// requiredSet 作用域中应当存在链接的模块和平台
+type requiredSet struct {
+	modules   map[string]bool // 按目录名，nil 表示全部模块
+	platforms map[string]bool
+}
+
+// requires 检查模块在平台上是否应当存在链接
+func (r requiredSet) requires(mod *Module, platform string) bool {
+	if !r.platforms[platform] {
+		return false
+	}
+	return r.modules == nil || r.modules[filepath.Base(mod.Path)]
+}
+
+// requiredLinks 返回作用域中缺失时计入 missing_default 的链接
+// 全局作用域为全部模块在 default_platforms 上的链接；项目作用域只包括项目清单声明的模块，
+// 平台为清单中的 platforms（未设置时为 default_platforms），没有清单时不统计
+func requiredLinks(cfg *Config, scope string) requiredSet {
+	r := requiredSet{platforms: make(map[string]bool)}
+	platforms := cfg.DefaultPlatforms
+	if scope == ScopeProject {
+		r.modules = make(map[string]bool)
+		if cfg.ProjectRoot == "" {
+			return r
+		}
+		m, err := LoadManifest(filepath.Join(cfg.ProjectRoot, ManifestName))
+		if err != nil {
+			return r
+		}
+		for _, mod := range m.Modules {
+			r.modules[mod.Name] = true
+		}
+		if len(m.Platforms) > 0 {
+			platforms = m.Platforms
+		}
+	}
+	for _, key := range platforms {
+		r.platforms[key] = true
+	}
+	return r
+}
+
+// ExitCode 返回 --check 模式的退出码，全部健康时为 0
+func (r StatusReport) ExitCode() int {
+	code := 0
+	for _, mr := range r.Modules {
+		for _, ls := range mr.Links {
+			switch ls.State {
+			case LinkStateBroken:
+				code |= ExitBroken
+			case LinkStateBlocked:
+				code |= ExitBlocked
+			case LinkStateOutdated, LinkStateModified:
+				code |= ExitDrifted
+			}
+		}
+	}
+	if r.Summary.MissingDefault > 0 {
+		code |= ExitMissingDefault
+	}
+	return code
+}
+
+// InfoReport sk info 的输出
+type InfoReport struct {
+	SchemaVersion int          `json:"schema_version"`
+	Module        ModuleReport `json:"module"`
+}
+
+// PlatformReport 单个平台
+type PlatformReport struct {
+	Key      string `json:"key"`
+	Name     string `json:"name"`
+	Project  string `json:"project"`
+	Global   string `json:"global"`
+	SkillDir string `json:"skill_dir"`
+	AgentDir string `json:"agent_dir"`
+	LinkMode string `json:"link_mode"`
+	Default  bool   `json:"default"`
+}
+
+// PlatformsReport sk platforms 的输出
+type PlatformsReport struct {
+	SchemaVersion int              `json:"schema_version"`
+	Platforms     []PlatformReport `json:"platforms"`
+}
+
+// NewPlatformsReport 生成平台列表报告（按 platform_order 排列，未设置时按 key 排序）
+func NewPlatformsReport(cfg *Config) PlatformsReport {
+	defaults := make(map[string]bool)
+	for _, key := range cfg.DefaultPlatforms {
+		defaults[key] = true
+	}
+
+	keys := cfg.GetOrderedPlatformKeys()
+	if len(cfg.PlatformOrder) == 0 {
+		sort.Strings(keys)
+	}
+
+	report := PlatformsReport{SchemaVersion: ReportSchemaVersion, Platforms: []PlatformReport{}}
+	for _, key := range keys {
+		p := cfg.Platforms[key]
+		report.Platforms = append(report.Platforms, PlatformReport{
+			Key:      key,
+			Name:     p.Name,
+			Project:  p.Project,
+			Global:   p.Global,
+			SkillDir: p.SkillDir,
+			AgentDir: p.AgentDir,
+			LinkMode: p.GetLinkMode(),
+			Default:  defaults[key],
+		})
+	}
+	return report
+}
+
+// PlanReport use/sync --dry-run 的输出
+type PlanReport struct {
+	SchemaVersion int      `json:"schema_version"`
+	ProjectRoot   string   `json:"project_root,omitempty"` // 计划包含项目作用域时的项目目录
+	Operations    []LinkOp `json:"operations"`
+}
+
+// NewPlanReport 生成分发计划报告
+func NewPlanReport(plan *Plan) PlanReport {
+	ops := plan.Ops
+	if ops == nil {
+		ops = []LinkOp{}
+	}
+	return PlanReport{SchemaVersion: ReportSchemaVersion, Operations: ops}
+}
@@ -0,0 +1,178 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInspectLink(t *testing.T) {
+	tmp := t.TempDir()
+	source := filepath.Join(tmp, "skill", "foo")
+	os.MkdirAll(source, 0755)
+	os.WriteFile(filepath.Join(source, "SKILL.md"), []byte("v1"), 0644)
+	dir := filepath.Join(tmp, "skills")
+	os.MkdirAll(dir, 0755)
+
+	os.Symlink(source, filepath.Join(dir, "ok"))
+	Distribute(source, filepath.Join(dir, "relative"), LinkModeRelative)
+	os.Symlink("../elsewhere", filepath.Join(dir, "relative-broken"))
+	os.Symlink("/elsewhere", filepath.Join(dir, "broken"))
+	os.MkdirAll(filepath.Join(dir, "blocked"), 0755)
+	Distribute(source, filepath.Join(dir, "copy"), LinkModeCopy)
+	Distribute(source, filepath.Join(dir, "modified"), LinkModeCopy)
+	os.WriteFile(filepath.Join(dir, "modified", "SKILL.md"), []byte("edited"), 0644)
+
+	tests := []struct {
+		name  string
+		state string
+		mode  string
+	}{
+		{"ok", LinkStateOK, LinkModeSymlink},
+		{"broken", LinkStateBroken, LinkModeSymlink},
+		{"relative", LinkStateOK, LinkModeRelative},
+		{"relative-broken", LinkStateBroken, LinkModeRelative},
+		{"blocked", LinkStateBlocked, ""},
+		{"copy", LinkStateOK, LinkModeCopy},
+		{"modified", LinkStateModified, LinkModeCopy},
+		{"missing", LinkStateMissing, ""},
+	}
+	for _, tt := range tests {
+		ls := InspectLink(source, filepath.Join(dir, tt.name))
+		if ls.State != tt.state || ls.Mode != tt.mode {
+			t.Errorf("%s: got state=%s mode=%s, expected state=%s mode=%s", tt.name, ls.State, ls.Mode, tt.state, tt.mode)
+		}
+	}
+}
+
+func TestStatusSummary(t *testing.T) {
+	var s StatusSummary
+	for _, state := range []string{LinkStateOK, LinkStateBroken, LinkStateBlocked, LinkStateOutdated, LinkStateMissing} {
+		s.Add(state)
+	}
+	if s.Healthy != 1 || s.Broken != 2 || s.Drifted != 1 || s.Missing != 1 {
+		t.Errorf("unexpected summary: %+v", s)
+	}
+}
+
+func TestParseOutputFlags(t *testing.T) {
+	format, rest, err := ParseOutputFlags([]string{"status", "--json"})
+	if err != nil || format != FormatJSON || len(rest) != 1 || rest[0] != "status" {
+		t.Errorf("unexpected result: %s %v %v", format, rest, err)
+	}
+
+	format, rest, err = ParseOutputFlags([]string{"--format", "yaml", "list", "--format=json"})
+	if err != nil || format != FormatJSON || len(rest) != 1 {
+		t.Errorf("unexpected result: %s %v %v", format, rest, err)
+	}
+
+	if _, _, err := ParseOutputFlags([]string{"--format", "xml"}); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	report := InfoReport{
+		SchemaVersion: ReportSchemaVersion,
+		Module:        ModuleReport{Name: "true", Category: "skill", Links: []LinkState{{Platform: "claude", State: LinkStateOK}}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteReport(&buf, FormatJSON, report); err != nil {
+		t.Fatalf("WriteReport json failed: %v", err)
+	}
+	var decoded InfoReport
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || decoded.Module.Name != "true" {
+		t.Errorf("unexpected json output: %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteReport(&buf, FormatYAML, report); err != nil {
+		t.Fatalf("WriteReport yaml failed: %v", err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "schema_version: 1\n") {
+		t.Errorf("expected schema_version first, got:\n%s", out)
+	}
+	if !strings.Contains(out, `name: "true"`) {
+		t.Errorf("expected string that looks like a bool to stay quoted, got:\n%s", out)
+	}
+}
+
+func TestStatusReportExitCode(t *testing.T) {
+	tmp := t.TempDir()
+	mod := &Module{Name: "foo", Category: "skill", Path: filepath.Join(tmp, "skill", "foo")}
+	os.MkdirAll(mod.Path, 0755)
+
+	cfg := &Config{
+		Platforms: map[string]Platform{
+			"one": {Global: filepath.Join(tmp, "one"), SkillDir: "skills"},
+			"two": {Global: filepath.Join(tmp, "two"), SkillDir: "skills"},
+		},
+	}
+
+	// 未配置默认平台时，未链接不算失败
+	report, _ := NewStatusReport(cfg, []*Module{mod})
+	if code := report.ExitCode(); code != 0 {
+		t.Errorf("expected exit code 0, got %d", code)
+	}
+
+	cfg.DefaultPlatforms = []string{"one"}
+	report, _ = NewStatusReport(cfg, []*Module{mod})
+	if report.Summary.MissingDefault != 1 {
+		t.Errorf("expected 1 missing default link, got %d", report.Summary.MissingDefault)
+	}
+	if code := report.ExitCode(); code != ExitMissingDefault {
+		t.Errorf("expected exit code %d, got %d", ExitMissingDefault, code)
+	}
+
+	// 默认平台指向错误位置，另一平台被实体目录占用
+	os.MkdirAll(filepath.Join(tmp, "one", "skills"), 0755)
+	os.Symlink("/elsewhere", filepath.Join(tmp, "one", "skills", "foo"))
+	os.MkdirAll(filepath.Join(tmp, "two", "skills", "foo"), 0755)
+
+	report, _ = NewStatusReport(cfg, []*Module{mod})
+	if code := report.ExitCode(); code != ExitBroken|ExitBlocked {
+		t.Errorf("expected exit code %d, got %d", ExitBroken|ExitBlocked, code)
+	}
+}
+
+func TestStatusReportProjectScope(t *testing.T) {
+	tmp := t.TempDir()
+	declared := &Module{Name: "declared", Category: "skill", Path: filepath.Join(tmp, "pool", "skill", "declared")}
+	other := &Module{Name: "other", Category: "skill", Path: filepath.Join(tmp, "pool", "skill", "other")}
+	os.MkdirAll(declared.Path, 0755)
+	os.MkdirAll(other.Path, 0755)
+
+	cfg := &Config{
+		DefaultPlatforms: []string{"one"},
+		Platforms: map[string]Platform{
+			"one": {Global: filepath.Join(tmp, "global"), Project: ".one/", SkillDir: "skills"},
+		},
+	}
+	project := filepath.Join(tmp, "project")
+	os.MkdirAll(project, 0755)
+	cfg.SetProjectRoot(project)
+
+	// 没有项目清单时项目作用域不统计缺失链接，全局作用域照常统计
+	report, _ := NewStatusReport(cfg, []*Module{declared, other}, AllScopes...)
+	if report.Summary.MissingDefault != 2 {
+		t.Errorf("expected 2 missing global default links, got %d", report.Summary.MissingDefault)
+	}
+
+	os.WriteFile(filepath.Join(project, ManifestName), []byte("[[module]]\nname = \"declared\"\n"), 0644)
+	report, _ = NewStatusReport(cfg, []*Module{declared, other}, ScopeProject)
+	if report.Summary.MissingDefault != 1 || report.Summary.Missing != 2 {
+		t.Errorf("expected only the declared module to be required, got %+v", report.Summary)
+	}
+
+	os.MkdirAll(filepath.Join(project, ".one", "skills"), 0755)
+	os.Symlink(declared.Path, filepath.Join(project, ".one", "skills", "declared"))
+	report, _ = NewStatusReport(cfg, []*Module{declared, other}, ScopeProject)
+	if code := report.ExitCode(); code != 0 {
+		t.Errorf("expected exit code 0 with undeclared modules unlinked, got %d", code)
+	}
+}
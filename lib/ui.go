@@ -2,8 +2,10 @@ package lib
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"runtime"
+	"sort"
 	"time"
 
 	"golang.org/x/term"
@@ -51,6 +53,7 @@ func ShowHelp() {
 	fmt.Println()
 	fmt.Printf("%sADD SOURCE FORMATS%s\n", ColorBlue, ColorReset)
 	fmt.Printf("  %s%-28s%s %s\n", ColorGreen, "owner/repo", ColorReset, "GitHub shorthand")
+	fmt.Printf("  %s%-28s%s %s\n", ColorGreen, "owner/repo@<ref>", ColorReset, "Pin to a branch, tag or commit")
 	fmt.Printf("  %s%-28s%s %s\n", ColorGreen, "owner/repo/path/to/skill", ColorReset, "GitHub with subpath")
 	fmt.Printf("  %s%-28s%s %s\n", ColorGreen, "https://github.com/o/r", ColorReset, "Full GitHub URL")
 	fmt.Printf("  %s%-28s%s %s\n", ColorGreen, "https://gitlab.com/o/r", ColorReset, "GitLab URL")
@@ -60,8 +63,17 @@ func ShowHelp() {
 	fmt.Printf("%sUSE OPTIONS%s\n", ColorBlue, ColorReset)
 	fmt.Printf("  %s%-20s%s %s\n", ColorGreen, "--global", ColorReset, "Use global scope (default)")
 	fmt.Printf("  %s%-20s%s %s\n", ColorGreen, "--project", ColorReset, "Use project scope")
+	fmt.Printf("  %s%-20s%s %s\n", ColorGreen, "--all-scopes", ColorReset, "Use both global and project scope")
 	fmt.Printf("  %s%-20s%s %s\n", ColorGreen, "--as <name>", ColorReset, "Override link name")
+	fmt.Printf("  %s%-20s%s %s\n", ColorGreen, "--relative", ColorReset, "Create relative symlinks")
 	fmt.Printf("  %s%-20s%s %s\n", ColorGreen, "--dry-run", ColorReset, "Preview without making changes")
+	fmt.Printf("  %s%-20s%s %s\n", ColorGreen, "--atomic", ColorReset, "Roll back every platform if one fails")
+
+	fmt.Println()
+	fmt.Printf("%sGLOBAL OPTIONS%s\n", ColorBlue, ColorReset)
+	fmt.Printf("  %s%-20s%s %s\n", ColorGreen, "--json", ColorReset, "Machine-readable output (list, status, info, platforms, lint, search, --dry-run)")
+	fmt.Printf("  %s%-20s%s %s\n", ColorGreen, "--format <fmt>", ColorReset, "Output format: text (default), json, yaml")
+	fmt.Printf("  %s%-20s%s %s\n", ColorGreen, "--root <dir>", ColorReset, "Project root for --project (default: nearest .git, .skillkit.toml or platform dir)")
 
 	fmt.Println()
 	fmt.Printf("%sEXAMPLES%s\n", ColorBlue, ColorReset)
@@ -71,6 +83,9 @@ func ShowHelp() {
 	fmt.Printf("  %ssk use my-skill claude%s           Distribute to specific platform\n", ColorGreen, ColorReset)
 	fmt.Printf("  %ssk list%s                          Show all modules\n", ColorGreen, ColorReset)
 	fmt.Printf("  %ssk remove my-skill%s               Remove from all platforms\n", ColorGreen, ColorReset)
+	fmt.Printf("  %ssk list --project%s                Show links in the current project\n", ColorGreen, ColorReset)
+	fmt.Printf("  %ssk new skill my-skill%s            Create a skill from a template\n", ColorGreen, ColorReset)
+	fmt.Printf("  %ssk lint ./my-skill%s               Check a skill before adding it\n", ColorGreen, ColorReset)
 
 	fmt.Println()
 	fmt.Printf("%sUNINSTALL%s\n", ColorBlue, ColorReset)
@@ -80,6 +95,11 @@ func ShowHelp() {
 	fmt.Println()
 }
 
+// IsInteractive 检查标准输入是否为终端（可以显示交互式菜单）
+func IsInteractive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
 // ShowVersion 显示版本信息
 func ShowVersion() {
 	fmt.Printf("\nSkill Kit version %s\n", Version)
@@ -398,6 +418,7 @@ func ModuleListMenu(cfg *Config) ModuleListResult {
 	selected := 0
 	maxOptions := len(modules)
 	selectAll := false // 全选状态
+	inProject := cfg.InProject()
 
 	HideCursor()
 	defer ShowCursor()
@@ -422,13 +443,23 @@ func ModuleListMenu(cfg *Config) ModuleListResult {
 
 		// 显示当前选中模块的已同步平台
 		currentMod := modules[selected]
-		syncedPlatforms := getSyncedPlatformNames(cfg, currentMod)
+		syncedPlatforms := getSyncedPlatformNames(cfg, currentMod, ScopeGlobal)
 		if len(syncedPlatforms) > 0 {
 			fmt.Printf("  %s %s\n", Gray("Synced:"), Cyan(joinStrings(syncedPlatforms, ", ")))
 		} else {
 			fmt.Printf("  %s %s\n", Gray("Synced:"), Gray("(none)"))
 		}
 
+		// 在项目中运行时，同时显示项目目录中的链接
+		if inProject {
+			projectPlatforms := getSyncedPlatformNames(cfg, currentMod, ScopeProject)
+			if len(projectPlatforms) > 0 {
+				fmt.Printf("  %s %s\n", Gray("Project:"), Cyan(joinStrings(projectPlatforms, ", ")))
+			} else {
+				fmt.Printf("  %s %s\n", Gray("Project:"), Gray("(none)"))
+			}
+		}
+
 		// 显示当前选中模块的描述（带换行对齐）
 		if currentMod.Description != "" {
 			printWrappedDesc(currentMod.Description)
@@ -495,16 +526,13 @@ func getDefaultPlatformNames(cfg *Config) []string {
 	return names
 }
 
-// getSyncedPlatformNames 获取已同步的平台名称列表
-func getSyncedPlatformNames(cfg *Config, mod *Module) []string {
+// getSyncedPlatformNames 获取模块在指定作用域已同步的平台名称列表
+func getSyncedPlatformNames(cfg *Config, mod *Module, scope string) []string {
 	var names []string
-	for key, p := range cfg.Platforms {
-		targetDir := ResolvePath(p.Global, p.GetCategoryDir(mod.Category))
-		targetPath := targetDir + "/" + mod.GetLinkName(key)
-		if IsSymlink(targetPath) {
-			names = append(names, p.Name)
-		}
+	for _, key := range GetSyncedPlatformKeys(cfg, mod, scope) {
+		names = append(names, cfg.Platforms[key].Name)
 	}
+	sort.Strings(names)
 	return names
 }
 
@@ -573,6 +601,7 @@ func ListMenu(cfg *Config) {
 	mode := 0
 	selected := 0                              // 平台视图中的选中项
 	platformKeys := cfg.GetOrderedPlatformKeys() // 使用有序的平台列表
+	scopes := tuiScopes(cfg)
 
 	HideCursor()
 	defer ShowCursor()
@@ -592,10 +621,10 @@ func ListMenu(cfg *Config) {
 					// 获取已同步的平台（按顺序）
 					for _, key := range platformKeys {
 						p := cfg.Platforms[key]
-						targetDir := ResolvePath(p.Global, p.GetCategoryDir(mod.Category))
-						targetPath := targetDir + "/" + mod.GetLinkName(key)
-						if IsSymlink(targetPath) {
-							fmt.Printf("      %s %s\n", Green(IconSuccess), p.Name)
+						for _, scope := range scopes {
+							if target := ModuleTarget(p, key, mod, scope); target != "" && IsDistributed(target) {
+								fmt.Printf("      %s %s%s\n", Green(IconSuccess), p.Name, scopeLabel(scope))
+							}
 						}
 					}
 				}
@@ -617,11 +646,11 @@ func ListMenu(cfg *Config) {
 				// 获取该平台下的已同步模块
 				hasModule := false
 				for _, mod := range modules {
-					targetDir := ResolvePath(p.Global, p.GetCategoryDir(mod.Category))
-					targetPath := targetDir + "/" + mod.GetLinkName(key)
-					if IsSymlink(targetPath) {
-						fmt.Printf("      %s %s %s\n", Green(IconSuccess), mod.Name, Gray("("+mod.Category+")"))
-						hasModule = true
+					for _, scope := range scopes {
+						if target := ModuleTarget(p, key, mod, scope); target != "" && IsDistributed(target) {
+							fmt.Printf("      %s %s %s%s\n", Green(IconSuccess), mod.Name, Gray("("+mod.Category+")"), scopeLabel(scope))
+							hasModule = true
+						}
 					}
 				}
 				if !hasModule {
@@ -706,11 +735,33 @@ func ConfirmDialog(message string) bool {
 	}
 }
 
+// ScopedPlatform 平台及其作用域
+type ScopedPlatform struct {
+	Key   string
+	Scope string
+}
+
 // ModuleDetailResult 模块详情操作结果
 type ModuleDetailResult struct {
-	Action   string   // "apply", "back", "quit"
-	ToSync   []string // 需要同步的平台
-	ToRemove []string // 需要删除的平台
+	Action   string           // "apply", "back", "quit"
+	ToSync   []ScopedPlatform // 需要同步的平台
+	ToRemove []ScopedPlatform // 需要删除的平台
+}
+
+// tuiScopes 交互界面显示的作用域：在项目中运行时同时包含项目作用域
+func tuiScopes(cfg *Config) []string {
+	if cfg.InProject() {
+		return AllScopes
+	}
+	return []string{ScopeGlobal}
+}
+
+// scopeLabel 项目作用域的标注
+func scopeLabel(scope string) string {
+	if scope == ScopeProject {
+		return Gray(" [project]")
+	}
+	return ""
 }
 
 // ModuleDetailMenu 模块详情菜单（选中=同步，取消=删除）
@@ -718,22 +769,30 @@ func ModuleDetailMenu(cfg *Config, mod *Module) ModuleDetailResult {
 	// 获取所有平台，并检查当前同步状态
 	type platformState struct {
 		key      string
+		scope    string
 		name     string
 		selected bool
 		synced   bool // 当前是否已同步
 	}
 
+	// 在项目中运行时，每个平台的全局和项目目录各占一行
 	platforms := make([]platformState, 0)
-	for key, p := range cfg.Platforms {
-		targetDir := ResolvePath(p.Global, p.GetCategoryDir(mod.Category))
-		targetPath := targetDir + "/" + mod.GetLinkName(key)
-		synced := IsSymlink(targetPath)
-		platforms = append(platforms, platformState{
-			key:      key,
-			name:     p.Name,
-			selected: synced, // 默认选中已同步的
-			synced:   synced,
-		})
+	for _, key := range cfg.GetOrderedPlatformKeys() {
+		p := cfg.Platforms[key]
+		for _, scope := range tuiScopes(cfg) {
+			target := ModuleTarget(p, key, mod, scope)
+			if target == "" {
+				continue
+			}
+			synced := IsDistributed(target)
+			platforms = append(platforms, platformState{
+				key:      key,
+				scope:    scope,
+				name:     p.Name + scopeLabel(scope),
+				selected: synced, // 默认选中已同步的
+				synced:   synced,
+			})
+		}
 	}
 
 	selected := 0
@@ -797,12 +856,12 @@ func ModuleDetailMenu(cfg *Config, mod *Module) ModuleDetailResult {
 			platforms[selected].selected = !platforms[selected].selected
 		case "LEFT":
 			// 返回时自动应用变更
-			var toSync, toRemove []string
+			var toSync, toRemove []ScopedPlatform
 			for _, p := range platforms {
 				if p.selected && !p.synced {
-					toSync = append(toSync, p.key)
+					toSync = append(toSync, ScopedPlatform{Key: p.key, Scope: p.scope})
 				} else if !p.selected && p.synced {
-					toRemove = append(toRemove, p.key)
+					toRemove = append(toRemove, ScopedPlatform{Key: p.key, Scope: p.scope})
 				}
 			}
 			return ModuleDetailResult{Action: "apply", ToSync: toSync, ToRemove: toRemove}
@@ -856,8 +915,8 @@ func (s *Spinner) Stop() {
 	fmt.Print("\r\033[2K") // 清除行
 }
 
-// PrintTable 打印表格（用于 dry-run）
-func PrintTable(headers []string, rows [][]string) {
+// PrintTable 把表格打印到 w（用于 dry-run）
+func PrintTable(w io.Writer, headers []string, rows [][]string) {
 	// 计算列宽
 	widths := make([]int, len(headers))
 	for i, h := range headers {
@@ -872,42 +931,42 @@ func PrintTable(headers []string, rows [][]string) {
 	}
 
 	// 打印表头
-	fmt.Print("  ")
+	fmt.Fprint(w, "  ")
 	for i, h := range headers {
-		fmt.Printf("%s%-*s%s  ", ColorBlue, widths[i], h, ColorReset)
+		fmt.Fprintf(w, "%s%-*s%s  ", ColorBlue, widths[i], h, ColorReset)
 	}
-	fmt.Println()
+	fmt.Fprintln(w)
 
 	// 打印分隔线
-	fmt.Print("  ")
-	for _, w := range widths {
-		for j := 0; j < w; j++ {
-			fmt.Print("─")
+	fmt.Fprint(w, "  ")
+	for _, width := range widths {
+		for j := 0; j < width; j++ {
+			fmt.Fprint(w, "─")
 		}
-		fmt.Print("  ")
+		fmt.Fprint(w, "  ")
 	}
-	fmt.Println()
+	fmt.Fprintln(w)
 
 	// 打印数据行
 	for _, row := range rows {
-		fmt.Print("  ")
+		fmt.Fprint(w, "  ")
 		for i, cell := range row {
 			if i < len(widths) {
 				// 根据内容着色
 				colored := cell
-				if cell == "CREATE" {
+				if cell == ActionCreate {
 					colored = Green(cell)
-				} else if cell == "UPDATE" {
+				} else if cell == ActionUpdate {
 					colored = Yellow(cell)
 				} else if cell == "SKIP" {
 					colored = Gray(cell)
-				} else if cell == "ERROR" {
+				} else if cell == "ERROR" || cell == ActionBlocked {
 					colored = Red(cell)
 				}
-				fmt.Printf("%-*s  ", widths[i]+len(colored)-len(cell), colored)
+				fmt.Fprintf(w, "%-*s  ", widths[i]+len(colored)-len(cell), colored)
 			}
 		}
-		fmt.Println()
+		fmt.Fprintln(w)
 	}
 }
 
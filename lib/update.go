@@ -0,0 +1,133 @@
+package lib
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// FileChange 模块文件变更
+type FileChange struct {
+	Path string // 相对路径
+	Kind string // "added", "removed", "modified"
+}
+
+// DiffDirs 比较两个目录的文件内容，返回按路径排序的变更列表
+func DiffDirs(oldDir, newDir string) ([]FileChange, error) {
+	oldFiles, err := listFiles(oldDir)
+	if err != nil {
+		return nil, err
+	}
+	newFiles, err := listFiles(newDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []FileChange
+	for rel := range newFiles {
+		if !oldFiles[rel] {
+			changes = append(changes, FileChange{Path: rel, Kind: "added"})
+			continue
+		}
+		oldData, err := os.ReadFile(filepath.Join(oldDir, rel))
+		if err != nil {
+			return nil, err
+		}
+		newData, err := os.ReadFile(filepath.Join(newDir, rel))
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(oldData, newData) {
+			changes = append(changes, FileChange{Path: rel, Kind: "modified"})
+		}
+	}
+	for rel := range oldFiles {
+		if !newFiles[rel] {
+			changes = append(changes, FileChange{Path: rel, Kind: "removed"})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+func listFiles(dir string) (map[string]bool, error) {
+	files := make(map[string]bool)
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = true
+		return nil
+	})
+	return files, err
+}
+
+// UpdateSkill 原地替换已安装的技能
+// 目标路径保持不变，因此指向该模块的软链接不受影响。
+// 新版本不包含 skillkit.toml 时保留本地的 skillkit.toml（链接别名）。
+func UpdateSkill(skill *DiscoveredSkill, cfg *Config) error {
+	if err := ValidateDirName(skill.Name); err != nil {
+		return err
+	}
+	if err := ValidateCategory(skill.Category); err != nil {
+		return err
+	}
+
+	targetBase := filepath.Join(cfg.RepoPath, skill.Category)
+	targetDir := filepath.Join(targetBase, skill.Name)
+
+	if _, err := os.Stat(targetDir); err != nil {
+		return &ModuleNotFoundError{Name: skill.Name}
+	}
+
+	// 先复制到同级临时目录，避免复制失败时破坏现有模块
+	newDir := filepath.Join(targetBase, "."+skill.Name+".skillkit-new")
+	oldDir := filepath.Join(targetBase, "."+skill.Name+".skillkit-old")
+	os.RemoveAll(newDir)
+	os.RemoveAll(oldDir)
+
+	if err := os.MkdirAll(newDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := copyDir(skill.Path, newDir); err != nil {
+		os.RemoveAll(newDir)
+		return fmt.Errorf("failed to copy skill: %w", err)
+	}
+
+	localConfig := filepath.Join(targetDir, "skillkit.toml")
+	newConfig := filepath.Join(newDir, "skillkit.toml")
+	if data, err := os.ReadFile(localConfig); err == nil {
+		if _, err := os.Stat(newConfig); os.IsNotExist(err) {
+			if err := os.WriteFile(newConfig, data, 0644); err != nil {
+				os.RemoveAll(newDir)
+				return fmt.Errorf("failed to preserve skillkit.toml: %w", err)
+			}
+		}
+	}
+
+	// 交换目录
+	if err := os.Rename(targetDir, oldDir); err != nil {
+		os.RemoveAll(newDir)
+		return fmt.Errorf("failed to replace skill: %w", err)
+	}
+	if err := os.Rename(newDir, targetDir); err != nil {
+		os.Rename(oldDir, targetDir)
+		os.RemoveAll(newDir)
+		return fmt.Errorf("failed to replace skill: %w", err)
+	}
+
+	return os.RemoveAll(oldDir)
+}
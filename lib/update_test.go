@@ -0,0 +1,83 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiffDirs(t *testing.T) {
+	oldDir := t.TempDir()
+	newDir := t.TempDir()
+
+	os.WriteFile(filepath.Join(oldDir, "SKILL.md"), []byte("v1"), 0644)
+	os.WriteFile(filepath.Join(oldDir, "same.txt"), []byte("same"), 0644)
+	os.WriteFile(filepath.Join(oldDir, "gone.txt"), []byte("gone"), 0644)
+
+	os.WriteFile(filepath.Join(newDir, "SKILL.md"), []byte("v2"), 0644)
+	os.WriteFile(filepath.Join(newDir, "same.txt"), []byte("same"), 0644)
+	os.MkdirAll(filepath.Join(newDir, "scripts"), 0755)
+	os.WriteFile(filepath.Join(newDir, "scripts", "run.sh"), []byte("echo"), 0644)
+
+	changes, err := DiffDirs(oldDir, newDir)
+	if err != nil {
+		t.Fatalf("DiffDirs failed: %v", err)
+	}
+
+	expected := []FileChange{
+		{Path: "SKILL.md", Kind: "modified"},
+		{Path: "gone.txt", Kind: "removed"},
+		{Path: "scripts/run.sh", Kind: "added"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %v", len(expected), changes)
+	}
+	for i, c := range changes {
+		if c != expected[i] {
+			t.Errorf("change %d = %+v, expected %+v", i, c, expected[i])
+		}
+	}
+}
+
+func TestUpdateSkillKeepsSymlinksAndLocalConfig(t *testing.T) {
+	repo := t.TempDir()
+	cfg := &Config{RepoPath: repo}
+
+	installed := filepath.Join(repo, "skill", "my-skill")
+	os.MkdirAll(installed, 0755)
+	os.WriteFile(filepath.Join(installed, "SKILL.md"), []byte("old"), 0644)
+	os.WriteFile(filepath.Join(installed, "stale.txt"), []byte("stale"), 0644)
+	os.WriteFile(filepath.Join(installed, "skillkit.toml"), []byte("[link]\ndefault = \"alias\"\n"), 0644)
+
+	link := filepath.Join(t.TempDir(), "skills", "my-skill")
+	if err := CreateSymlink(installed, link, false); err != nil {
+		t.Fatalf("CreateSymlink failed: %v", err)
+	}
+
+	upstream := t.TempDir()
+	os.WriteFile(filepath.Join(upstream, "SKILL.md"), []byte("new"), 0644)
+
+	skill := &DiscoveredSkill{Name: "my-skill", Category: "skill", Path: upstream}
+	if err := UpdateSkill(skill, cfg); err != nil {
+		t.Fatalf("UpdateSkill failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(link, "SKILL.md"))
+	if err != nil {
+		t.Fatalf("symlink no longer resolves: %v", err)
+	}
+	if string(data) != "new" {
+		t.Errorf("expected updated content, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(installed, "stale.txt")); !os.IsNotExist(err) {
+		t.Error("files removed upstream should be removed locally")
+	}
+	if _, err := os.Stat(filepath.Join(installed, "skillkit.toml")); err != nil {
+		t.Error("local skillkit.toml should be preserved")
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(repo, "skill"))
+	if len(entries) != 1 {
+		t.Errorf("expected no leftover staging directories, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,209 @@
+package lib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	toml "github.com/pelletier/go-toml/v2"
+)
+
+// VendorMarkerName vendor 副本中记录来源的文件
+const VendorMarkerName = ".skillkit-vendor.toml"
+
+// LinkModeVendor 项目中提交的 vendor 副本（只出现在 LinkState.Mode 中，不是平台的 link_mode）
+const LinkModeVendor = "vendor"
+
+// vendor 操作结果
+const (
+	VendorCreated   = "created"
+	VendorUpdated   = "updated"
+	VendorUnchanged = "unchanged"
+)
+
+// VendorMarker vendor 副本的来源记录
+// 只记录与机器无关的信息，副本可以随项目提交
+type VendorMarker struct {
+	Module     string    `toml:"module"` // 模块目录名（不受 [link].default 影响）
+	Category   string    `toml:"category"`
+	Source     string    `toml:"source,omitempty"` // 锁文件中记录的来源
+	Commit     string    `toml:"commit,omitempty"`
+	Hash       string    `toml:"hash"` // 写入时的内容哈希（不含记录文件）
+	VendoredAt time.Time `toml:"vendored_at"`
+}
+
+// VendoredCopy 项目中找到的 vendor 副本
+type VendoredCopy struct {
+	Platform string
+	Target   string
+	Marker   *VendorMarker
+	Err      error // 来源记录无法解析或不合法，此时 Marker 为 nil
+}
+
+// ReadVendorMarker 读取 vendor 副本的来源记录
+// 记录随项目提交，不可信：模块名须为单个目录名，类别须为 skill 或 agent
+func ReadVendorMarker(target string) (*VendorMarker, error) {
+	path := filepath.Join(target, VendorMarkerName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var marker VendorMarker
+	if err := toml.Unmarshal(data, &marker); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := ValidateDirName(marker.Module); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := ValidateCategory(marker.Category); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &marker, nil
+}
+
+// IsVendored 检查目标是否为 vendor 副本
+func IsVendored(target string) bool {
+	if IsSymlink(target) {
+		return false
+	}
+	_, err := ReadVendorMarker(target)
+	return err == nil
+}
+
+// VendorState 比较 vendor 副本与仓库中的模块，返回 CopyStateOK / CopyStateOutdated / CopyStateModified
+func VendorState(source, target string) (string, error) {
+	marker, err := ReadVendorMarker(target)
+	if err != nil {
+		return "", err
+	}
+
+	targetHash, err := HashDir(target)
+	if err != nil {
+		return "", err
+	}
+	if targetHash != marker.Hash {
+		return CopyStateModified, nil
+	}
+
+	sourceHash, err := HashDir(source)
+	if err != nil {
+		return "", err
+	}
+	if sourceHash != marker.Hash {
+		return CopyStateOutdated, nil
+	}
+	return CopyStateOK, nil
+}
+
+// VendorModule 把模块复制到目标路径并写入来源记录，返回 Vendor* 之一
+// 目标已存在时只替换未被本地修改的 vendor 副本；来源从仓库锁文件读取
+func VendorModule(cfg *Config, mod *Module, target string) (string, error) {
+	target = ResolvePath(target)
+	dirName := filepath.Base(mod.Path)
+
+	action := VendorCreated
+	if _, err := os.Lstat(target); err == nil {
+		if !IsVendored(target) {
+			return "", fmt.Errorf("target exists and is not a vendored copy: %s", target)
+		}
+		state, err := VendorState(mod.Path, target)
+		if err != nil {
+			return "", err
+		}
+		switch state {
+		case CopyStateOK:
+			return VendorUnchanged, nil
+		case CopyStateModified:
+			return "", fmt.Errorf("vendored copy has local changes, refusing to overwrite: %s", target)
+		}
+		action = VendorUpdated
+	}
+
+	hash, err := HashDir(mod.Path)
+	if err != nil {
+		return "", err
+	}
+	marker := VendorMarker{
+		Module:     dirName,
+		Category:   mod.Category,
+		Hash:       hash,
+		VendoredAt: time.Now().UTC().Truncate(time.Second),
+	}
+	if lock, err := LoadLockfile(LockfilePath(cfg)); err == nil {
+		if entry := lock.Find(mod.Category, dirName); entry != nil {
+			marker.Source = entry.Source
+			marker.Commit = entry.Commit
+		}
+	}
+	data, err := toml.Marshal(marker)
+	if err != nil {
+		return "", err
+	}
+
+	// 先写到临时目录，成功后再替换旧副本
+	tmp := filepath.Join(filepath.Dir(target), "."+filepath.Base(target)+".skillkit-tmp")
+	os.RemoveAll(tmp)
+	if err := os.MkdirAll(tmp, 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %v", err)
+	}
+	if err := copyDir(mod.Path, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return "", fmt.Errorf("failed to copy %s: %w", mod.Path, err)
+	}
+	if err := os.WriteFile(filepath.Join(tmp, VendorMarkerName), data, 0644); err != nil {
+		os.RemoveAll(tmp)
+		return "", err
+	}
+
+	if err := os.RemoveAll(target); err != nil {
+		os.RemoveAll(tmp)
+		return "", err
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.RemoveAll(tmp)
+		return "", err
+	}
+	return action, nil
+}
+
+// FindVendored 列出平台项目目录中的 vendor 副本（按平台 key、路径排序）
+// 来源记录不合法的副本也会列出，并在 Err 中给出原因
+func FindVendored(cfg *Config, platKeys []string) ([]VendoredCopy, error) {
+	keys := append([]string(nil), platKeys...)
+	sort.Strings(keys)
+
+	var copies []VendoredCopy
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		p := cfg.Platforms[key]
+		for _, category := range []string{"skill", "agent"} {
+			dir := p.GetTargetDir(ScopeProject, category)
+			if dir == "" || seen[dir] {
+				continue
+			}
+			seen[dir] = true
+
+			entries, err := os.ReadDir(dir)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range entries {
+				target := filepath.Join(dir, entry.Name())
+				if IsSymlink(target) {
+					continue
+				}
+				if _, err := os.Stat(filepath.Join(target, VendorMarkerName)); err != nil {
+					continue
+				}
+				marker, err := ReadVendorMarker(target)
+				copies = append(copies, VendoredCopy{Platform: key, Target: target, Marker: marker, Err: err})
+			}
+		}
+	}
+	return copies, nil
+}
@@ -0,0 +1,152 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVendorModule(t *testing.T) {
+	tmp := t.TempDir()
+	cfg := &Config{
+		RepoPath: filepath.Join(tmp, "repo"),
+		Platforms: map[string]Platform{
+			"claude": {Project: ".claude/", SkillDir: "skills"},
+		},
+	}
+	cfg.SetProjectRoot(filepath.Join(tmp, "project"))
+
+	mod := &Module{Name: "foo", Category: "skill", Path: filepath.Join(cfg.RepoPath, "skill", "foo")}
+	os.MkdirAll(mod.Path, 0755)
+	os.WriteFile(filepath.Join(mod.Path, "SKILL.md"), []byte("v1"), 0644)
+	SaveLockfile(LockfilePath(cfg), &Lockfile{Modules: []LockEntry{{Name: "foo", Category: "skill", Source: "acme/skills/foo", Commit: "abc123"}}})
+
+	target := ModuleTarget(cfg.Platforms["claude"], "claude", mod, ScopeProject)
+	if action, err := VendorModule(cfg, mod, target); err != nil || action != VendorCreated {
+		t.Fatalf("VendorModule = %s, %v", action, err)
+	}
+	marker, err := ReadVendorMarker(target)
+	if err != nil || marker.Module != "foo" || marker.Source != "acme/skills/foo" || marker.Commit != "abc123" {
+		t.Fatalf("unexpected marker: %+v %v", marker, err)
+	}
+	if ls := InspectLink(mod.Path, target); ls.State != LinkStateOK || ls.Mode != LinkModeVendor {
+		t.Errorf("expected ok vendored copy, got %+v", ls)
+	}
+	if action, _ := VendorModule(cfg, mod, target); action != VendorUnchanged {
+		t.Errorf("expected unchanged, got %s", action)
+	}
+
+	// 源变更后可以刷新
+	os.WriteFile(filepath.Join(mod.Path, "SKILL.md"), []byte("v2"), 0644)
+	if ls := InspectLink(mod.Path, target); ls.State != LinkStateOutdated {
+		t.Errorf("expected outdated, got %s", ls.State)
+	}
+	copies, _ := FindVendored(cfg, []string{"claude"})
+	if len(copies) != 1 || copies[0].Target != target {
+		t.Fatalf("expected one vendored copy, got %+v", copies)
+	}
+	if action, err := VendorModule(cfg, mod, target); err != nil || action != VendorUpdated {
+		t.Fatalf("expected updated, got %s, %v", action, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(target, "SKILL.md")); string(data) != "v2" {
+		t.Errorf("expected refreshed content, got %q", data)
+	}
+
+	// 本地修改的副本不会被覆盖或删除
+	os.WriteFile(filepath.Join(target, "SKILL.md"), []byte("edited"), 0644)
+	if _, err := VendorModule(cfg, mod, target); err == nil {
+		t.Error("expected error when vendored copy was modified")
+	}
+	if err := Undistribute(target); err == nil {
+		t.Error("expected Undistribute to refuse a modified vendored copy")
+	}
+}
+
+func TestVendorModuleProtectsRealDirs(t *testing.T) {
+	tmp := t.TempDir()
+	cfg := &Config{RepoPath: filepath.Join(tmp, "repo")}
+	mod := &Module{Name: "foo", Category: "skill", Path: filepath.Join(tmp, "repo", "skill", "foo")}
+	os.MkdirAll(mod.Path, 0755)
+
+	target := filepath.Join(tmp, "project", ".claude", "skills", "foo")
+	os.MkdirAll(target, 0755)
+	if _, err := VendorModule(cfg, mod, target); err == nil {
+		t.Error("expected error for a real directory that is not a vendored copy")
+	}
+}
+
+func TestVendorModuleLinkDefault(t *testing.T) {
+	tmp := t.TempDir()
+	cfg := &Config{
+		RepoPath: filepath.Join(tmp, "repo"),
+		Platforms: map[string]Platform{
+			"claude": {Project: ".claude/", SkillDir: "skills"},
+		},
+	}
+	cfg.SetProjectRoot(filepath.Join(tmp, "project"))
+
+	dir := filepath.Join(cfg.RepoPath, "skill", "foo")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte("v1"), 0644)
+	os.WriteFile(filepath.Join(dir, "skillkit.toml"), []byte("[link]\ndefault = \"bar\"\n"), 0644)
+	SaveLockfile(LockfilePath(cfg), &Lockfile{Modules: []LockEntry{{Name: "foo", Category: "skill", Source: "acme/skills/foo", Commit: "abc123"}}})
+
+	mod, err := FindModule(cfg, "foo")
+	if err != nil || mod.Name != "bar" {
+		t.Fatalf("expected module named by [link].default, got %+v, %v", mod, err)
+	}
+	target := ModuleTarget(cfg.Platforms["claude"], "claude", mod, ScopeProject)
+	if filepath.Base(target) != "bar" {
+		t.Fatalf("expected target named bar, got %s", target)
+	}
+	if _, err := VendorModule(cfg, mod, target); err != nil {
+		t.Fatalf("VendorModule failed: %v", err)
+	}
+
+	// 记录的是目录名，可以据此找回模块和锁文件条目
+	marker, err := ReadVendorMarker(target)
+	if err != nil || marker.Module != "foo" || marker.Source != "acme/skills/foo" {
+		t.Fatalf("unexpected marker: %+v %v", marker, err)
+	}
+	if _, err := FindModule(cfg, marker.Module); err != nil {
+		t.Errorf("module recorded in marker not found: %v", err)
+	}
+	if ls := InspectLink(mod.Path, target); ls.State != LinkStateOK {
+		t.Errorf("expected ok vendored copy, got %s", ls.State)
+	}
+}
+
+func TestFindVendoredRejectsHostileMarkers(t *testing.T) {
+	tmp := t.TempDir()
+	cfg := &Config{
+		RepoPath: filepath.Join(tmp, "repo"),
+		Platforms: map[string]Platform{
+			"claude": {Project: ".claude/", SkillDir: "skills", AgentDir: "agents"},
+		},
+	}
+	cfg.SetProjectRoot(filepath.Join(tmp, "project"))
+	os.MkdirAll(filepath.Join(tmp, ".ssh"), 0755)
+
+	markers := map[string]string{
+		"escape":   "module = \"../../../.ssh\"\ncategory = \"skill\"\n",
+		"category": "module = \"foo\"\ncategory = \"../..\"\n",
+	}
+	for name, content := range markers {
+		dir := filepath.Join(tmp, "project", ".claude", "skills", name)
+		os.MkdirAll(dir, 0755)
+		os.WriteFile(filepath.Join(dir, VendorMarkerName), []byte(content), 0644)
+		if _, err := ReadVendorMarker(dir); err == nil {
+			t.Errorf("%s: expected ReadVendorMarker to reject the marker", name)
+		}
+	}
+
+	copies, err := FindVendored(cfg, []string{"claude"})
+	if err != nil || len(copies) != 2 {
+		t.Fatalf("expected both copies reported, got %+v, %v", copies, err)
+	}
+	for _, c := range copies {
+		if c.Err == nil || c.Marker != nil {
+			t.Errorf("expected an error for %s, got %+v", c.Target, c)
+		}
+	}
+}